@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// principal is who a token authenticates: a user, and the scopes the token
+// grants, like read:file
+type principal struct {
+	user   string
+	scopes []string
+}
+
+// tokens maps the bearer tokens accepted by the server to their principal.
+// When there are none, authentication is off.
+var tokens map[string]principal
+
+type userKey struct{}
+
+// parseTokens parses the -auth-tokens flag, a comma-separated list of
+// token=user pairs. The user may be followed by the scopes of the token,
+// each after a +, e.g. token=user+read:file.
+func parseTokens(value string) (map[string]principal, error) {
+	parsed := map[string]principal{}
+	for _, pair := range splitList(value) {
+		token, user, ok := strings.Cut(pair, "=")
+		scopes := strings.Split(user, "+")
+		user, scopes = scopes[0], scopes[1:]
+		if !ok || token == "" || user == "" {
+			return nil, fmt.Errorf("invalid token %q, expected token=user", pair)
+		}
+		for _, scope := range scopes {
+			if scope == "" {
+				return nil, fmt.Errorf("invalid token %q, empty scope", pair)
+			}
+		}
+		parsed[token] = principal{user: user, scopes: scopes}
+	}
+	return parsed, nil
+}
+
+// authenticate returns the principal of the bearer token of r. ok is false
+// if r has a token which isn't valid.
+func authenticate(r *http.Request) (p principal, ok bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return principal{}, true
+	}
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return principal{}, false
+	}
+	for known, p := range tokens {
+		// compared in constant time so timing doesn't reveal tokens
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			return p, true
+		}
+	}
+	return principal{}, false
+}
+
+// withUser puts the principal authenticated by the request in its context,
+// rejecting requests with an invalid token
+func withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(tokens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, ok := authenticate(r)
+		if !ok {
+			unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, p)))
+	})
+}
+
+// userFrom returns the user of the request of ctx, or "" if anonymous
+func userFrom(ctx context.Context) string {
+	p, _ := ctx.Value(userKey{}).(principal)
+	return p.user
+}
+
+// hasScope reports whether the token of the request of ctx grants scope
+func hasScope(ctx context.Context, scope string) bool {
+	p, _ := ctx.Value(userKey{}).(principal)
+	for _, granted := range p.scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// canReadFiles reports whether the request of ctx may read the files of
+// documents: -file-scope is not set, or the token of the request has it
+func canReadFiles(ctx context.Context) bool {
+	return *fileScope == "" || hasScope(ctx, *fileScope)
+}
+
+// anonymous reports whether authentication is on and r has no user
+func anonymous(r *http.Request) bool {
+	return len(tokens) > 0 && userFrom(r.Context()) == ""
+}
+
+// unauthorized rejects a request lacking a valid token
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// requireUser rejects anonymous requests when authentication is on
+func requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if anonymous(r) {
+			unauthorized(w)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// introspectionOnly reports whether the operation selected by operationName
+// only selects introspection fields, like __schema and __type, which
+// anonymous clients may query
+func introspectionOnly(document *ast.Document, operationName string) bool {
+	operation := selectOperation(document, operationName)
+	if operation == nil || operation.Operation != ast.OperationTypeQuery {
+		return false
+	}
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return introspectionSelections(operation.SelectionSet, fragments, map[string]bool{})
+}
+
+func introspectionSelections(selectionSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visited map[string]bool) bool {
+	if selectionSet == nil {
+		return true
+	}
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(selection.Name.Value, "__") {
+				return false
+			}
+		case *ast.InlineFragment:
+			if !introspectionSelections(selection.SelectionSet, fragments, visited) {
+				return false
+			}
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := fragments[name]
+			if !ok {
+				return false
+			}
+			// fragment cycles are invalid, leave them to validation
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if !introspectionSelections(fragment.SelectionSet, fragments, visited) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnonymousIntrospection(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &tokens, map[string]principal{"secret": {user: "alice"}})
+	handler := withUser(documentHandler(newTestSchema(t)))
+	post := func(query, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(query))
+		r.Header.Set("Content-Type", "application/graphql")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return serve(handler, r)
+	}
+
+	w := post(`{__schema{queryType{name}}}`, "")
+	if got := lookup(decodeResponse(t, w), "data", "__schema", "queryType", "name"); w.Code != http.StatusOK || got != "Query" {
+		t.Errorf("anonymous introspection: status %d, query type %v, want 200 and Query", w.Code, got)
+	}
+	w = post(`{list{id}}`, "")
+	if w.Code != http.StatusUnauthorized || lookup(decodeResponse(t, w), "errors") == nil {
+		t.Errorf("anonymous list: status %d, want 401 with an error", w.Code)
+	}
+	w = post(`{list{id}}`, "secret")
+	if w.Code != http.StatusOK || lookup(decodeResponse(t, w), "data", "list") == nil {
+		t.Errorf("authenticated list: status %d, want 200 with the list", w.Code)
+	}
+}
+
+func TestFileScope(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &tokens, map[string]principal{
+		"scoped":   {user: "alice", scopes: []string{"read:file"}},
+		"unscoped": {user: "bob"},
+	})
+	setVar(t, fileScope, "read:file")
+	handler := withUser(documentHandler(newTestSchema(t)))
+	file := func(token string) interface{} {
+		r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(`{document(id:1){name,file}}`))
+		r.Header.Set("Content-Type", "application/graphql")
+		r.Header.Set("Authorization", "Bearer "+token)
+		return lookup(decodeResponse(t, serve(handler, r)), "data", "document", "file")
+	}
+	if got := file("scoped"); got != "SGVsbG8sIFdvcmxkIQ==" {
+		t.Errorf("scoped client: file %v, want it", got)
+	}
+	if got := file("unscoped"); got != nil {
+		t.Errorf("unscoped client: file %v, want null", got)
+	}
+
+	// the file endpoint checks the scope too
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /document/{id}/file", fileHandler)
+	for token, want := range map[string]int{"scoped": http.StatusOK, "unscoped": http.StatusForbidden} {
+		r := httptest.NewRequest(http.MethodGet, "/document/1/file", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		if w := serve(withUser(mux), r); w.Code != want {
+			t.Errorf("%s client: file download status %d, want %d", token, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore keeps file contents, addressed by the reference Put returns
+type BlobStore interface {
+	Put(data []byte) (ref string, err error)
+	Get(ref string) ([]byte, error)
+}
+
+// blobs keeps the files of documents when -blob-dir is set; documents then
+// only hold a reference to their file
+var blobs BlobStore
+
+// fsBlobStore keeps blobs as files of a directory, named by the SHA-256 of
+// their content so identical files are stored once
+type fsBlobStore struct {
+	dir string
+}
+
+func newFSBlobStore(dir string) (*fsBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fsBlobStore{dir: dir}, nil
+}
+
+func (s *fsBlobStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	ref := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.dir, ref)
+	if _, err := os.Stat(path); err == nil {
+		return ref, nil
+	}
+	// write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(s.dir, ref+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return ref, os.Rename(tmp.Name(), path)
+}
+
+func (s *fsBlobStore) Get(ref string) ([]byte, error) {
+	// refs are hex digests, anything else could escape the directory
+	if _, err := hex.DecodeString(ref); err != nil || len(ref) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob reference %q", ref)
+	}
+	return os.ReadFile(filepath.Join(s.dir, ref))
+}
+
+// withFile returns document with its file loaded from the blob store, if it
+// is kept there
+func withFile(document Document) (Document, error) {
+	if document.BlobRef == "" || document.File != "" {
+		return document, nil
+	}
+	data, err := blobs.Get(document.BlobRef)
+	if err != nil {
+		return document, &internalError{err: err}
+	}
+	document.File = encodeFile(data, document.fileEncoded)
+	return document, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFSBlobStore(t *testing.T) {
+	blobStore, err := newFSBlobStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("newFSBlobStore: %v", err)
+	}
+	ref, err := blobStore.Put([]byte("Hello, World!"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, err := blobStore.Get(ref); err != nil || string(data) != "Hello, World!" {
+		t.Errorf("Get(%q) = %q, %v", ref, data, err)
+	}
+	// identical content is stored once
+	if again, err := blobStore.Put([]byte("Hello, World!")); err != nil || again != ref {
+		t.Errorf("Put of the same content = %q, %v, want %q", again, err, ref)
+	}
+	if entries, _ := os.ReadDir(blobStore.dir); len(entries) != 1 {
+		t.Errorf("%d files in the blob directory, want 1", len(entries))
+	}
+	for _, invalid := range []string{"../secret", "abc", ""} {
+		if _, err := blobStore.Get(invalid); err == nil {
+			t.Errorf("Get(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestDocumentsWithBlobs(t *testing.T) {
+	blobStore, err := newFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSBlobStore: %v", err)
+	}
+	setVar(t, &blobs, BlobStore(blobStore))
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+
+	// documents only keep a reference, the file is loaded when selected
+	document, err := store.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if document.File != "" || document.BlobRef == "" {
+		t.Errorf("stored document has file %q and blobRef %q, want only a blobRef", document.File, document.BlobRef)
+	}
+	// the reference is internal to the server
+	if encoded, err := json.Marshal(document); err != nil || strings.Contains(string(encoded), document.BlobRef) {
+		t.Errorf("encoded document %s, %v, want it without the blob reference", encoded, err)
+	}
+	data := mustExecute(t, schema, "{document(id:1){file,fileSize}}", nil)
+	if got := lookup(data, "document", "file"); got != "SGVsbG8sIFdvcmxkIQ==" {
+		t.Errorf("file = %v, want the seeded one", got)
+	}
+	if got := lookup(data, "document", "fileSize"); got != 13.0 {
+		t.Errorf("fileSize = %v, want 13", got)
+	}
+	data = mustExecute(t, schema, `mutation{update(id:1,file:"bmV3"){document{file}}}`, nil)
+	if got := lookup(data, "update", "document", "file"); got != "bmV3" {
+		t.Errorf("updated file = %v, want bmV3", got)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// costEntry is the cost of a query run at a time
+type costEntry struct {
+	at   time.Time
+	cost int
+}
+
+// costBudget bounds the total cost of the queries of each user over a
+// rolling window
+type costBudget struct {
+	mu     sync.Mutex
+	budget int
+	window time.Duration
+	spent  map[string][]costEntry // by user, oldest first
+}
+
+// budgets limits query costs per user, if -user-cost-budget is set
+var budgets *costBudget
+
+func newCostBudget(budget int, window time.Duration) *costBudget {
+	return &costBudget{budget: budget, window: window, spent: map[string][]costEntry{}}
+}
+
+// spend records a query of user costing cost if it fits in what is left of
+// their budget. Otherwise it returns false and the time from which it will
+// fit, or the zero time if it never will.
+func (b *costBudget) spend(user string, cost int, now time.Time) (bool, time.Time) {
+	if cost > b.budget {
+		return false, time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.spent[user]
+	// forget the queries which left the window
+	for len(entries) > 0 && !entries[0].at.After(now.Add(-b.window)) {
+		entries = entries[1:]
+	}
+	total := 0
+	for _, entry := range entries {
+		total += entry.cost
+	}
+	if total+cost > b.budget {
+		// the query fits once enough of the oldest queries leave the window
+		for _, entry := range entries {
+			total -= entry.cost
+			if total+cost <= b.budget {
+				b.spent[user] = entries
+				return false, entry.at.Add(b.window)
+			}
+		}
+	}
+	b.spent[user] = append(entries, costEntry{at: now, cost: cost})
+	return true, time.Time{}
+}
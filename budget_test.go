@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCostBudget(t *testing.T) {
+	budget := newCostBudget(5, time.Minute)
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, spend := range []struct {
+		user    string
+		cost    int
+		at      time.Duration
+		ok      bool
+		resetAt time.Duration
+	}{
+		{"alice", 3, 0, true, 0},
+		{"alice", 2, 10 * time.Second, true, 0},
+		{"alice", 1, 20 * time.Second, false, time.Minute},
+		// budgets are per user
+		{"bob", 5, 20 * time.Second, true, 0},
+		{"alice", 3, time.Minute + time.Second, true, 0},
+		{"alice", 6, 2 * time.Minute, false, -1},
+	} {
+		ok, resetAt := budget.spend(spend.user, spend.cost, start.Add(spend.at))
+		wantResetAt := start.Add(spend.resetAt)
+		if spend.resetAt <= 0 {
+			wantResetAt = time.Time{}
+		}
+		if ok != spend.ok || !resetAt.Equal(wantResetAt) {
+			t.Errorf("spend %d: %v, %v, want %v, %v", i, ok, resetAt, spend.ok, wantResetAt)
+		}
+	}
+}
+
+func TestUserCostBudget(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &tokens, map[string]principal{"alice-token": {user: "alice"}, "bob-token": {user: "bob"}})
+	schema := newTestSchema(t)
+	query := `{document(id:1){name}}`
+	req := graphqlRequest{Query: query}
+	req.parse()
+	cost, _ := queryCost(schema, req.document, "")
+	setVar(t, &budgets, newCostBudget(2*cost, time.Minute))
+	handler := withUser(documentHandler(schema))
+	post := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(query))
+		r.Header.Set("Content-Type", "application/graphql")
+		r.Header.Set("Authorization", "Bearer "+token)
+		return serve(handler, r)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := post("alice-token"); w.Code != http.StatusOK {
+			t.Fatalf("query %d: status %d: %s", i, w.Code, w.Body)
+		}
+	}
+	w := post("alice-token")
+	response := decodeResponse(t, w)
+	errors, _ := response["errors"].([]interface{})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || len(errors) != 1 {
+		t.Fatalf("over budget: status %d, Retry-After %q, response %v, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"), response)
+	}
+	if message, _ := lookup(errors[0], "message").(string); message != "rate limit exceeded" || lookup(errors[0], "extensions", "resetAt") == nil {
+		t.Errorf("error %v, want rate limit exceeded with resetAt", errors[0])
+	}
+	if w := post("bob-token"); w.Code != http.StatusOK {
+		t.Errorf("other user: status %d, want 200", w.Code)
+	}
+}
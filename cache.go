@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+)
+
+// queryCache caches the results of queries. An entry stays valid as long as
+// the documents the query read keep their version, so updating a document
+// only invalidates the queries that read it. Queries that read the whole
+// collection (lists, searches, aggregates) are invalidated by any change.
+type queryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]cacheEntry
+	// generation is incremented on every document change
+	generation atomic.Uint64
+}
+
+type cacheEntry struct {
+	result     *graphql.Result
+	versions   map[int64]int // version of each document read, 0 if missing
+	all        bool          // whether the query read the whole collection
+	generation uint64
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{size: size, entries: map[string]cacheEntry{}}
+}
+
+// cacheKey returns the key of the cache entry of req
+func cacheKey(req graphqlRequest) string {
+	variables, _ := json.Marshal(req.Variables)
+	return req.path + "\x00" + strconv.FormatBool(req.readsFiles) + "\x00" + req.OperationName + "\x00" + string(variables) + "\x00" + req.Query
+}
+
+// get returns the cached result of req, if it is still valid
+func (c *queryCache) get(ctx context.Context, req graphqlRequest) (*graphql.Result, bool) {
+	key := cacheKey(req)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	if entry.all && entry.generation != c.generation.Load() {
+		c.remove(key)
+		return nil, false
+	}
+	for id, version := range entry.versions {
+		if currentVersion(ctx, id) != version {
+			c.remove(key)
+			return nil, false
+		}
+	}
+	return entry.result, true
+}
+
+// put caches the result of req, given what the query read
+func (c *queryCache) put(req graphqlRequest, result *graphql.Result, reads *readTracker, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		// make room by dropping an arbitrary entry
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[cacheKey(req)] = cacheEntry{
+		result:     result,
+		versions:   reads.versions,
+		all:        reads.all,
+		generation: generation,
+	}
+}
+
+func (c *queryCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// changed records a document change, invalidating the entries of queries
+// that read the whole collection
+func (c *queryCache) changed() {
+	c.generation.Add(1)
+}
+
+// currentVersion returns the version of the stored document, 0 if missing
+func currentVersion(ctx context.Context, id int64) int {
+	document, err := store.Get(ctx, id)
+	if err != nil {
+		return 0
+	}
+	return document.Version
+}
+
+// readTracker records the documents read while resolving a query
+type readTracker struct {
+	mu       sync.Mutex
+	versions map[int64]int
+	all      bool
+	// uncacheable is set by resolvers whose results differ between runs
+	// on the same documents, like randomDocument
+	uncacheable bool
+}
+
+type readTrackerKey struct{}
+
+// withReadTracker returns a context recording the reads done with it
+func withReadTracker(ctx context.Context) (context.Context, *readTracker) {
+	reads := &readTracker{versions: map[int64]int{}}
+	return context.WithValue(ctx, readTrackerKey{}, reads), reads
+}
+
+func trackerFrom(ctx context.Context) *readTracker {
+	reads, _ := ctx.Value(readTrackerKey{}).(*readTracker)
+	return reads
+}
+
+// trackingStore records the reads of its queries into the read tracker of
+// their context, if any
+type trackingStore struct {
+	Store
+}
+
+func (s trackingStore) List(ctx context.Context) ([]Document, error) {
+	trackAllReads(ctx)
+	return s.Store.List(ctx)
+}
+
+func (s trackingStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	trackAllReads(ctx)
+	return s.Store.ListSorted(ctx, field)
+}
+
+func (s trackingStore) Search(ctx context.Context, term string) ([]Document, error) {
+	trackAllReads(ctx)
+	return s.Store.Search(ctx, term)
+}
+
+func (s trackingStore) NextID(ctx context.Context) (int64, error) {
+	trackAllReads(ctx)
+	return s.Store.NextID(ctx)
+}
+
+func (s trackingStore) Get(ctx context.Context, id int64) (Document, error) {
+	document, err := s.Store.Get(ctx, id)
+	if reads := trackerFrom(ctx); reads != nil && (err == nil || errors.Is(err, errNotFound)) {
+		reads.mu.Lock()
+		reads.versions[id] = document.Version
+		reads.mu.Unlock()
+	}
+	return document, err
+}
+
+// trackAllReads records that the query of ctx depends on the whole
+// collection, so any change invalidates it
+func trackAllReads(ctx context.Context) {
+	if reads := trackerFrom(ctx); reads != nil {
+		reads.mu.Lock()
+		reads.all = true
+		reads.mu.Unlock()
+	}
+}
+
+// markUncacheable keeps the result of the query of ctx out of the cache
+func markUncacheable(ctx context.Context) {
+	if reads := trackerFrom(ctx); reads != nil {
+		reads.mu.Lock()
+		reads.uncacheable = true
+		reads.mu.Unlock()
+	}
+}
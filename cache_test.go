@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestQueryCache(t *testing.T) {
+	memory := newTestStore(t, testDocuments(2)...)
+	setVar(t, &store, Store(trackingStore{Store: memory}))
+	setVar(t, &queries, newQueryCache(10))
+	schema := newTestSchema(t)
+	query := func(query string) *graphql.Result {
+		return executeQuery(context.Background(), graphqlRequest{Query: query}, schema)
+	}
+
+	first := query("{document(id:1){name}}")
+	if again := query("{document(id:1){name}}"); again != first {
+		t.Error("document 1 isn't cached")
+	}
+	list := query("{list{name}}")
+
+	mustExecute(t, schema, `mutation{update(id:2,name:"Renamed"){document{id}}}`, nil)
+	if again := query("{document(id:1){name}}"); again != first {
+		t.Error("document 1 isn't cached anymore after an update of document 2")
+	}
+	if again := query("{list{name}}"); again == list {
+		t.Error("the list is still cached after an update")
+	}
+
+	mustExecute(t, schema, `mutation{update(id:1,name:"Renamed"){document{id}}}`, nil)
+	updated := query("{document(id:1){name}}")
+	if updated == first || lookup(updated.Data, "document", "name") != "Renamed" {
+		t.Errorf("document 1 after its update = %v, want Renamed", updated.Data)
+	}
+	// mutations are never cached
+	if query(`mutation{create(name:"Report"){document{id}}}`) == query(`mutation{create(name:"Report"){document{id}}}`) {
+		t.Error("a mutation was cached")
+	}
+}
+
+func TestQueryCacheSkipsRandomDocument(t *testing.T) {
+	memory := newTestStore(t, testDocuments(20)...)
+	setVar(t, &store, Store(trackingStore{Store: memory}))
+	setVar(t, &queries, newQueryCache(10))
+	schema := newTestSchema(t)
+
+	first := executeQuery(context.Background(), graphqlRequest{Query: "{randomDocument{id}}"}, schema)
+	seen := map[interface{}]bool{lookup(first.Data, "randomDocument", "id"): true}
+	for i := 0; i < 10; i++ {
+		result := executeQuery(context.Background(), graphqlRequest{Query: "{randomDocument{id}}"}, schema)
+		if result == first {
+			t.Fatal("randomDocument was served from the cache")
+		}
+		seen[lookup(result.Data, "randomDocument", "id")] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("random documents %v, want several documents", seen)
+	}
+}
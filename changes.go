@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sequencedEvent is a change event numbered in the order it happened
+type sequencedEvent struct {
+	Seq int64 `json:"seq"`
+	changeEvent
+}
+
+// changeLog keeps the latest change events for clients polling for them
+type changeLog struct {
+	mu      sync.Mutex
+	size    int
+	events  []sequencedEvent // oldest first
+	lastSeq int64
+	// notify is closed and replaced when an event is added, waking the
+	// clients waiting for one
+	notify chan struct{}
+}
+
+func newChangeLog(size int) *changeLog {
+	return &changeLog{size: size, notify: make(chan struct{})}
+}
+
+// changes are the events served by changesHandler, set up by main
+var changes *changeLog
+
+// add numbers event and keeps it, dropping the oldest event if the log is
+// full
+func (l *changeLog) add(event changeEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastSeq++
+	l.events = append(l.events, sequencedEvent{Seq: l.lastSeq, changeEvent: event})
+	if len(l.events) > l.size {
+		l.events = append(l.events[:0:0], l.events[len(l.events)-l.size:]...)
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// since returns the events with a sequence number greater than seq, the
+// sequence number of the last event, whether events after seq were dropped
+// or seq is from before a restart, and a channel closed on the next event
+func (l *changeLog) since(seq int64) ([]sequencedEvent, int64, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var events []sequencedEvent
+	for i, event := range l.events {
+		if event.Seq > seq {
+			events = append(events, l.events[i:]...)
+			break
+		}
+	}
+	dropped := seq < l.lastSeq && l.events[0].Seq > seq+1
+	return events, l.lastSeq, dropped || seq > l.lastSeq, l.notify
+}
+
+// changesResponse is the response of changesHandler
+type changesResponse struct {
+	Events []sequencedEvent `json:"events"`
+	// Last is the sequence number to poll since next
+	Last int64 `json:"last"`
+	// Missed reports that events newer than since were dropped from the log
+	// before being polled, or that since is unknown to the server, e.g. after
+	// a restart. The client should then reload the documents it watches.
+	Missed bool `json:"missed,omitempty"`
+}
+
+// changesHandler long-polls for change events: it responds with the events
+// newer than ?since=, waiting up to -poll-timeout for one if there are none
+func changesHandler(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil && r.URL.Query().Get("since") != "" {
+		http.Error(w, "since must be a sequence number", http.StatusBadRequest)
+		return
+	}
+
+	timer := time.NewTimer(*pollTimeout)
+	defer timer.Stop()
+	events, last, missed, notify := changes.since(since)
+wait:
+	for len(events) == 0 && !missed {
+		select {
+		case <-notify:
+			events, last, missed, notify = changes.since(since)
+		case <-timer.C:
+			break wait
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	response := changesResponse{Events: []sequencedEvent{}, Last: since, Missed: missed}
+	if len(events) > 0 || missed {
+		response.Last = last
+	}
+	if len(events) > 0 {
+		response.Events = events
+	}
+	if !canReadFiles(r.Context()) {
+		// events are a copy of the log, they can be changed
+		for i := range response.Events {
+			response.Events[i].Document.File = ""
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getChanges gets /document/changes?since=since
+func getChanges(since string) *httptest.ResponseRecorder {
+	return serve(http.HandlerFunc(changesHandler), httptest.NewRequest(http.MethodGet, "/document/changes?since="+since, nil))
+}
+
+// poll gets the changes since since and decodes them
+func poll(tb testing.TB, since string) changesResponse {
+	tb.Helper()
+	return decodeChanges(tb, getChanges(since))
+}
+
+func decodeChanges(tb testing.TB, w *httptest.ResponseRecorder) changesResponse {
+	tb.Helper()
+	var response changesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		tb.Fatalf("response %q: %v", w.Body.String(), err)
+	}
+	return response
+}
+
+func TestChangesPoll(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &changes, newChangeLog(10))
+	setVar(t, pollTimeout, 10*time.Second)
+	schema := newTestSchema(t)
+
+	polled := make(chan *httptest.ResponseRecorder)
+	go func() {
+		polled <- getChanges("0")
+	}()
+	mustExecute(t, schema, `mutation{update(id:1,name:"Renamed"){document{id}}}`, nil)
+	var response changesResponse
+	select {
+	case w := <-polled:
+		response = decodeChanges(t, w)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the poll didn't return after the mutation")
+	}
+	if len(response.Events) != 1 || response.Events[0].Type != "update" || response.Events[0].Document.Name != "Renamed" || response.Last != 1 {
+		t.Fatalf("polled %+v, want the update", response)
+	}
+
+	// polling since the last event waits for the next one, up to the timeout
+	setVar(t, pollTimeout, 10*time.Millisecond)
+	if response := poll(t, "1"); len(response.Events) != 0 || response.Last != 1 || response.Missed {
+		t.Errorf("polled %+v, want no events", response)
+	}
+}
+
+func TestChangesMissed(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &changes, newChangeLog(2))
+	schema := newTestSchema(t)
+	for _, name := range []string{"A", "B", "C"} {
+		mustExecute(t, schema, `mutation{update(id:1,name:"`+name+`"){document{id}}}`, nil)
+	}
+	// the first event was dropped
+	if response := poll(t, "0"); !response.Missed || len(response.Events) != 2 || response.Last != 3 {
+		t.Errorf("polled %+v, want the last 2 events and missed", response)
+	}
+	// a sequence number unknown to the server, e.g. from before a restart
+	if response := poll(t, "9"); !response.Missed || response.Last != 3 {
+		t.Errorf("polled %+v since 9, want missed", response)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers cross-origin clients may send
+const corsAllowedHeaders = "Authorization, Content-Type, Accept, X-Request-ID"
+
+// withCORS lets the origins of -cors-origins call the API from browsers.
+// Listed origins are echoed back and may send credentials; "*" lets any
+// other origin make requests without credentials. Preflight requests are
+// answered here, without reaching next.
+func withCORS(next http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	anyOrigin := false
+	for _, origin := range splitList(*corsOrigins) {
+		if origin == "*" {
+			anyOrigin = true
+			continue
+		}
+		allowed[strings.ToLower(origin)] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// responses differ by origin, caches must not share them
+		w.Header().Add("Vary", "Origin")
+		switch {
+		case allowed[strings.ToLower(origin)]:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		case anyOrigin:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		allowedOrigin := w.Header().Get("Access-Control-Allow-Origin") != ""
+		if allowedOrigin {
+			w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			// preflights of origins not allowed get no CORS headers, so
+			// browsers don't send the actual request
+			if allowedOrigin {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	setVar(t, corsOrigins, "https://app.example.com, https://admin.example.com")
+	reached := false
+	handler := withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		reached = false
+		r := httptest.NewRequest(method, "/document", nil)
+		r.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		return serve(handler, r)
+	}
+
+	w := request(http.MethodPost, "https://App.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://App.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" || !reached {
+		t.Errorf("allowed origin: Allow-Origin %q, Allow-Credentials %q, want the origin with credentials", got, w.Header().Get("Access-Control-Allow-Credentials"))
+	}
+
+	w = request(http.MethodPost, "https://evil.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("disallowed origin: Allow-Origin %q, want no CORS headers", got)
+	}
+
+	w = request(http.MethodOptions, "https://admin.example.com")
+	if w.Code != http.StatusNoContent || reached || w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" || w.Header().Get("Access-Control-Allow-Headers") != corsAllowedHeaders {
+		t.Errorf("preflight: status %d, headers %v, want 204 with the allowed methods and headers", w.Code, w.Header())
+	}
+	if w = request(http.MethodOptions, "https://evil.example.com"); w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("disallowed preflight: headers %v, want no CORS headers", w.Header())
+	}
+
+	// * allows other origins, without credentials
+	setVar(t, corsOrigins, "https://app.example.com,*")
+	handler = withCORS(http.NotFoundHandler())
+	w = request(http.MethodGet, "https://other.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("any origin: Allow-Origin %q, Allow-Credentials %q, want * without credentials", got, w.Header().Get("Access-Control-Allow-Credentials"))
+	}
+}
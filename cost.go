@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listCostMultiplier is the number of items assumed for a list field when
+// estimating query costs
+const listCostMultiplier = 10
+
+// queryCost estimates the cost of the operation selected by operationName:
+// every selected field costs one, multiplied by listCostMultiplier for every
+// list it is nested in. It returns false if the query didn't parse.
+func queryCost(schema graphql.Schema, document *ast.Document, operationName string) (int, bool) {
+	operation := selectOperation(document, operationName)
+	if operation == nil {
+		return 0, false
+	}
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var root graphql.Type = schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	c := costCounter{schema: schema, fragments: fragments, visiting: map[string]bool{}}
+	return c.selectionSet(operation.SelectionSet, root, 1), true
+}
+
+// costCounter walks the selections of an operation to sum their costs
+type costCounter struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	visiting  map[string]bool // fragments being walked, to stop on cycles
+}
+
+func (c costCounter) selectionSet(set *ast.SelectionSet, parent graphql.Type, multiplier int) int {
+	if set == nil {
+		return 0
+	}
+	cost := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost += multiplier
+			fieldType, isList := unwrapType(fieldType(parent, selection.Name.Value))
+			childMultiplier := multiplier
+			if isList {
+				childMultiplier *= listCostMultiplier
+			}
+			cost += c.selectionSet(selection.SelectionSet, fieldType, childMultiplier)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			cost += c.selectionSet(selection.SelectionSet, fragmentType, multiplier)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || c.visiting[name] {
+				continue
+			}
+			c.visiting[name] = true
+			fragmentType := parent
+			if fragment.TypeCondition != nil {
+				fragmentType = c.schema.Type(fragment.TypeCondition.Name.Value)
+			}
+			cost += c.selectionSet(fragment.SelectionSet, fragmentType, multiplier)
+			delete(c.visiting, name)
+		}
+	}
+	return cost
+}
+
+// fieldType returns the type of the named field of parent, or nil if parent
+// has no such field
+func fieldType(parent graphql.Type, name string) graphql.Type {
+	var fields graphql.FieldDefinitionMap
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		fields = parent.Fields()
+	case *graphql.Interface:
+		fields = parent.Fields()
+	}
+	if field, ok := fields[name]; ok {
+		return field.Type
+	}
+	return nil
+}
+
+// unwrapType strips the non-null and list wrappers of t, reporting whether
+// there was a list
+func unwrapType(t graphql.Type) (graphql.Type, bool) {
+	isList := false
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *graphql.List:
+			isList = true
+			t = wrapper.OfType
+		default:
+			return t, isList
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryCost(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	cost := func(query string) interface{} {
+		return execute(context.Background(), schema, query, nil).Extensions["cost"]
+	}
+	for _, test := range []struct {
+		query string
+		want  int
+	}{
+		{"{document(id:1){id}}", 2},
+		{"{document(id:1){id,name,file}}", 4},
+		// fields of list items count listCostMultiplier times
+		{"{list{id}}", 1 + listCostMultiplier},
+		{"{list{id,name}}", 1 + 2*listCostMultiplier},
+		{"query a{nextId} query b{list{id}}", 1}, // the first operation
+		{"fragment f on Document{id,name} {document(id:1){...f}}", 3},
+	} {
+		if got := cost(test.query); got != test.want {
+			t.Errorf("cost of %s = %v, want %d", test.query, got, test.want)
+		}
+	}
+	if got := cost("{document(id:1){id"); got != nil {
+		t.Errorf("cost of a syntax error = %v, want none", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"unsafe"
+)
+
+// storeInfo describes the store for /debug/store
+type storeInfo struct {
+	Type      string `json:"type"`
+	Documents int    `json:"documents"`
+	// MemoryBytes approximates the memory used by the documents, for
+	// stores keeping them in memory
+	MemoryBytes int `json:"memoryBytes,omitempty"`
+}
+
+// describe returns the type and size of the store
+func (s *memoryStore) describe() storeInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info := storeInfo{Type: "memory", Documents: len(s.records)}
+	if s.blobs != nil {
+		info.Type = "memory with blobs"
+	}
+	for _, r := range s.records {
+		info.MemoryBytes += int(unsafe.Sizeof(r)) + len(r.data) + len(r.document.Name) + len(r.document.ContentType) + len(r.document.BlobRef)
+		for _, tag := range r.document.Tags {
+			info.MemoryBytes += int(unsafe.Sizeof(tag)) + len(tag)
+		}
+	}
+	return info
+}
+
+// debugStoreHandler reports the type and size of the store
+func debugStoreHandler(w http.ResponseWriter, r *http.Request) {
+	// look through the stores wrapping the one keeping the documents
+	s := store
+	for {
+		switch wrapper := s.(type) {
+		case trackingStore:
+			s = wrapper.Store
+			continue
+		case retryStore:
+			s = wrapper.Store
+			continue
+		case *degradingStore:
+			s = wrapper.Store
+			continue
+		case sharedReadStore:
+			s = wrapper.Store
+			continue
+		case internalErrorStore:
+			s = wrapper.Store
+			continue
+		}
+		break
+	}
+	info := storeInfo{Type: "unknown"}
+	if describer, ok := s.(interface{ describe() storeInfo }); ok {
+		info = describer.describe()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
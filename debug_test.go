@@ -0,0 +1,22 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugStore(t *testing.T) {
+	newTestStore(t, testDocuments(3)...)
+	// the store is found through the stores wrapping it
+	setVar(t, &store, Store(trackingStore{retryStore{Store: store, attempts: 1}}))
+	w := serve(http.HandlerFunc(debugStoreHandler), httptest.NewRequest(http.MethodGet, "/debug/store", nil))
+	var info storeInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("response %q: %v", w.Body.String(), err)
+	}
+	if info.Type != "memory" || info.Documents != 3 || info.MemoryBytes <= 0 {
+		t.Errorf("store %+v, want a memory store of 3 documents", info)
+	}
+}
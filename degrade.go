@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// errUnavailable is returned by mutations while the store is failing writes
+var errUnavailable = errors.New("service unavailable: the store is failing writes, try again later")
+
+// degradingStore keeps serving reads when its store fails. Reads failing are
+// served from a snapshot of the documents last read or written. Once a
+// write fails, mutations fail right away with errUnavailable until
+// retryInterval has passed, when a write is let through to probe the store.
+type degradingStore struct {
+	Store
+	retryInterval time.Duration
+
+	mu         sync.Mutex
+	snapshot   []Document // in store order
+	degraded   bool
+	probeAfter time.Time
+}
+
+func newDegradingStore(s Store, retryInterval time.Duration) *degradingStore {
+	return &degradingStore{Store: s, retryInterval: retryInterval}
+}
+
+// storeFailure reports whether err means the store is failing, as opposed
+// to rejecting the operation or the request being canceled
+func storeFailure(err error) bool {
+	var conflict *conflictError
+	return err != nil && !errors.Is(err, errNotFound) && !errors.As(err, &conflict) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// write runs op unless the store is degraded and not due for a probe, and
+// updates the state of the store from its error
+func (s *degradingStore) write(op func() error) error {
+	s.mu.Lock()
+	if s.degraded && time.Now().Before(s.probeAfter) {
+		s.mu.Unlock()
+		return errUnavailable
+	}
+	s.mu.Unlock()
+	err := op()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if storeFailure(err) {
+		s.degraded = true
+		s.probeAfter = time.Now().Add(s.retryInterval)
+		return errUnavailable
+	}
+	s.degraded = false
+	return err
+}
+
+// remember updates the snapshot with document. s.mu must be held.
+func (s *degradingStore) remember(document Document) {
+	for i, known := range s.snapshot {
+		if known.ID == document.ID {
+			s.snapshot[i] = document
+			return
+		}
+	}
+	s.snapshot = append(s.snapshot, document)
+}
+
+// forget removes the document with the given id from the snapshot. s.mu
+// must be held.
+func (s *degradingStore) forget(id int64) {
+	for i, known := range s.snapshot {
+		if known.ID == id {
+			s.snapshot = append(s.snapshot[:i], s.snapshot[i+1:]...)
+			return
+		}
+	}
+}
+
+// snapshotCopy returns a copy of the snapshot
+func (s *degradingStore) snapshotCopy() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Document(nil), s.snapshot...)
+}
+
+func (s *degradingStore) List(ctx context.Context) ([]Document, error) {
+	documents, err := s.Store.List(ctx)
+	if storeFailure(err) {
+		return s.snapshotCopy(), nil
+	}
+	if err == nil {
+		s.mu.Lock()
+		s.snapshot = append([]Document(nil), documents...)
+		s.mu.Unlock()
+	}
+	return documents, err
+}
+
+func (s *degradingStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	documents, err := s.Store.ListSorted(ctx, field)
+	if err == nil {
+		// kept in id order, as the order of the store isn't known here
+		snapshot := append([]Document(nil), documents...)
+		sort.SliceStable(snapshot, func(i, j int) bool {
+			return snapshot[i].ID < snapshot[j].ID
+		})
+		s.mu.Lock()
+		s.snapshot = snapshot
+		s.mu.Unlock()
+	}
+	if !storeFailure(err) {
+		return documents, err
+	}
+	documents = s.snapshotCopy()
+	switch strings.ToLower(field) {
+	case "name":
+		collator := collate.New(language.Make(*collationLocale))
+		sort.SliceStable(documents, func(i, j int) bool {
+			return collator.CompareString(documents[i].Name, documents[j].Name) < 0
+		})
+	default:
+		sort.SliceStable(documents, func(i, j int) bool {
+			return documents[i].ID < documents[j].ID
+		})
+	}
+	return documents, nil
+}
+
+func (s *degradingStore) Get(ctx context.Context, id int64) (Document, error) {
+	document, err := s.Store.Get(ctx, id)
+	if err == nil {
+		s.mu.Lock()
+		s.remember(document)
+		s.mu.Unlock()
+	}
+	if !storeFailure(err) {
+		return document, err
+	}
+	for _, known := range s.snapshotCopy() {
+		if known.ID == id {
+			return known, nil
+		}
+	}
+	return Document{}, errNotFound
+}
+
+func (s *degradingStore) Create(ctx context.Context, document Document) (created Document, err error) {
+	err = s.write(func() error {
+		created, err = s.Store.Create(ctx, document)
+		return err
+	})
+	if err == nil {
+		s.mu.Lock()
+		s.remember(created)
+		s.mu.Unlock()
+	}
+	return created, err
+}
+
+func (s *degradingStore) Update(ctx context.Context, document Document) (updated Document, err error) {
+	err = s.write(func() error {
+		updated, err = s.Store.Update(ctx, document)
+		return err
+	})
+	if err == nil {
+		s.mu.Lock()
+		s.remember(updated)
+		s.mu.Unlock()
+	}
+	return updated, err
+}
+
+func (s *degradingStore) Delete(ctx context.Context, id int64) (deleted Document, err error) {
+	err = s.write(func() error {
+		deleted, err = s.Store.Delete(ctx, id)
+		return err
+	})
+	if err == nil {
+		s.mu.Lock()
+		s.forget(id)
+		s.mu.Unlock()
+	}
+	return deleted, err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// brokenStore fails every operation while broken is set
+type brokenStore struct {
+	Store
+	broken bool
+}
+
+var errBroken = errors.New("connection refused")
+
+func (s *brokenStore) List(ctx context.Context) ([]Document, error) {
+	if s.broken {
+		return nil, errBroken
+	}
+	return s.Store.List(ctx)
+}
+
+func (s *brokenStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	if s.broken {
+		return nil, errBroken
+	}
+	return s.Store.ListSorted(ctx, field)
+}
+
+func (s *brokenStore) Get(ctx context.Context, id int64) (Document, error) {
+	if s.broken {
+		return Document{}, errBroken
+	}
+	return s.Store.Get(ctx, id)
+}
+
+func (s *brokenStore) Create(ctx context.Context, document Document) (Document, error) {
+	if s.broken {
+		return Document{}, errBroken
+	}
+	return s.Store.Create(ctx, document)
+}
+
+func TestDegradingStore(t *testing.T) {
+	broken := &brokenStore{Store: newTestStore(t, testDocuments(2)...)}
+	setVar(t, &store, Store(newDegradingStore(broken, time.Hour)))
+	schema := newTestSchema(t)
+	// reads made while the store works are the snapshot
+	mustExecute(t, schema, `{list{id}}`, nil)
+
+	broken.broken = true
+	for _, query := range []string{`{list{id}}`, `{list(sortBy:"name"){id}}`} {
+		data := mustExecute(t, schema, query, nil)
+		if got := idsOf(data["list"]); got != "[1 2]" {
+			t.Errorf("%s: list %s while failing, want the snapshot", query, got)
+		}
+	}
+	if got := lookup(mustExecute(t, schema, `{document(id:2){name}}`, nil), "document", "name"); got != "Document 2" {
+		t.Errorf("document 2 = %v while failing, want Document 2", got)
+	}
+	for i := 0; i < 2; i++ {
+		result := execute(context.Background(), schema, `mutation{create(name:"New"){document{id}}}`, nil)
+		if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "service unavailable") {
+			t.Errorf("create %d: errors %v, want service unavailable", i, result.Errors)
+		}
+	}
+}
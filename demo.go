@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// demoDocumentCount is the number of documents generated by -demo-seed
+const demoDocumentCount = 24
+
+// demoEpoch is the time the timestamps of demo documents are counted from,
+// fixed so the same seed gives the same documents whenever it is run
+var demoEpoch = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// demoFile is a kind of file demo documents can have
+type demoFile struct {
+	extension   string
+	contentType string
+	content     string
+}
+
+var demoFiles = []demoFile{
+	{".txt", "text/plain; charset=utf-8", "Hello, World!"},
+	{".md", "text/markdown; charset=utf-8", "# Notes\n\n- first\n- second\n"},
+	{".json", "application/json", `{"status":"draft","pages":3}`},
+	{".html", "text/html; charset=utf-8", "<html><body><h1>Report</h1></body></html>"},
+	{".pdf", "application/pdf", "%PDF-1.4\n%demo\n"},
+	{".png", "image/png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"},
+	// documents without a file
+	{"", "", ""},
+}
+
+var (
+	demoAdjectives = []string{"Quarterly", "Draft", "Final", "Archived", "Shared", "Annual", "Weekly", "Internal"}
+	demoSubjects   = []string{"report", "budget", "roadmap", "minutes", "invoice", "diagram", "contract", "notes"}
+	demoTags       = []string{"finance", "legal", "design", "urgent", "review", "public", "archive"}
+)
+
+// demoDocuments returns the documents generated from seed, numbered from
+// base. They only depend on seed, not on the time or the server's random
+// numbers, so demos started with the same seed show the same documents.
+func demoDocuments(seed, base int64) []Document {
+	random := rand.New(rand.NewSource(seed))
+	documents := make([]Document, demoDocumentCount)
+	for i := range documents {
+		file := demoFiles[random.Intn(len(demoFiles))]
+		name := fmt.Sprintf("%s %s %d%s",
+			demoAdjectives[random.Intn(len(demoAdjectives))],
+			demoSubjects[random.Intn(len(demoSubjects))],
+			i+1, file.extension)
+		var tags []string
+		for _, tag := range demoTags {
+			if random.Intn(4) == 0 {
+				tags = append(tags, tag)
+			}
+		}
+		createdAt := demoEpoch.Add(time.Duration(random.Int63n(int64(365 * 24 * time.Hour)))).Truncate(time.Second)
+		updatedAt := createdAt
+		version := 1 + random.Intn(5)
+		if version > 1 {
+			updatedAt = createdAt.Add(time.Duration(random.Int63n(int64(30 * 24 * time.Hour)))).Truncate(time.Second)
+		}
+		documents[i] = Document{
+			ID:          base + int64(i),
+			Name:        name,
+			File:        base64.StdEncoding.EncodeToString([]byte(file.content)),
+			ContentType: file.contentType,
+			Tags:        tags,
+			Version:     version,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+		}
+		if file.content == "" {
+			documents[i].File = ""
+		}
+	}
+	return documents
+}
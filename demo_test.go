@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDemoDocumentsAreReproducible(t *testing.T) {
+	first := demoDocuments(42, 1)
+	if len(first) != demoDocumentCount {
+		t.Fatalf("%d demo documents, want %d", len(first), demoDocumentCount)
+	}
+	if again := demoDocuments(42, 1); !reflect.DeepEqual(first, again) {
+		t.Errorf("demo documents differ for the same seed:\n%v\n%v", first, again)
+	}
+	if other := demoDocuments(43, 1); reflect.DeepEqual(first, other) {
+		t.Error("demo documents are the same for different seeds")
+	}
+
+	// the set is varied, and valid as seed documents
+	contentTypes := map[string]bool{}
+	for i, document := range first {
+		if document.ID != int64(i+1) {
+			t.Errorf("document %d has id %d, want %d", i, document.ID, i+1)
+		}
+		contentTypes[document.ContentType] = true
+	}
+	if len(contentTypes) < 3 {
+		t.Errorf("content types %v, want several", contentTypes)
+	}
+	newTestStore(t, first...)
+}
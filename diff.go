@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// fieldDiff is a field whose value differs between two documents
+type fieldDiff struct {
+	Field  string `json:"field"`
+	AValue string `json:"aValue"`
+	BValue string `json:"bValue"`
+}
+
+// diffDocuments returns the fields differing between a and b, in a fixed
+// order. Tags are compared as sets and files by presence only.
+func diffDocuments(a, b Document) []fieldDiff {
+	diffs := []fieldDiff{}
+	compare := func(field, aValue, bValue string) {
+		if aValue != bValue {
+			diffs = append(diffs, fieldDiff{Field: field, AValue: aValue, BValue: bValue})
+		}
+	}
+	compare("name", a.Name, b.Name)
+	compare("contentType", a.ContentType, b.ContentType)
+	if !sameTags(a.Tags, b.Tags) {
+		compare("tags", strings.Join(a.Tags, ","), strings.Join(b.Tags, ","))
+	}
+	compare("hasFile", strconv.FormatBool(a.HasFile()), strconv.FormatBool(b.HasFile()))
+	return diffs
+}
+
+// sameTags reports whether a and b have the same tags, in any order
+func sameTags(a, b []string) bool {
+	a, b = uniqueTags(a), uniqueTags(b)
+	if len(a) != len(b) {
+		return false
+	}
+	set := map[string]bool{}
+	for _, tag := range a {
+		set[tag] = true
+	}
+	for _, tag := range b {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
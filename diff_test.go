@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	a := Document{ID: 1, Name: "Report", ContentType: "text/plain", Tags: []string{"draft", "2021"}, File: "SGVsbG8="}
+	for _, test := range []struct {
+		name string
+		b    Document
+		want string
+	}{
+		{"same", Document{ID: 2, Name: "Report", ContentType: "text/plain", Tags: []string{"2021", "draft"}, File: "V29ybGQ="}, "[]"},
+		{"name", Document{ID: 2, Name: "Other", ContentType: "text/plain", Tags: []string{"draft", "2021"}, File: "SGVsbG8="}, "[{name Report Other}]"},
+		{"tags and file", Document{ID: 2, Name: "Report", ContentType: "text/plain", Tags: []string{"draft"}}, "[{tags draft,2021 draft} {hasFile true false}]"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := fmt.Sprint(diffDocuments(a, test.b)); got != test.want {
+				t.Errorf("diff %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffQuery(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Report", ContentType: "text/plain"}, Document{ID: 2, Name: "Report", ContentType: "application/pdf"})
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `{diff(aId:1,bId:2){field,aValue,bValue}}`, nil)
+	if got := fmt.Sprint(lookup(data, "diff")); got != "[map[aValue:text/plain bValue:application/pdf field:contentType]]" {
+		t.Errorf("diff %s, want only the content type", got)
+	}
+	if result := execute(t.Context(), schema, `{diff(aId:1,bId:99){field}}`, nil); !result.HasErrors() {
+		t.Errorf("diff with a missing document succeeded: %v", result.Data)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Document contains infomation about one document
+
+type Document struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	File        string    `json:"file,omitempty"`
+	BlobRef     string    `json:"-"` // reference of the file in the blob store, internal to the server
+	ContentType string    `json:"contentType,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	OwnerID     int64     `json:"ownerId,omitempty"`
+	StoredSize  int       `json:"storedSize,omitempty"`
+	Version     int       `json:"version"` // incremented on every update
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// fileEncoded reports whether the file kept in the blob store is base64
+	// encoded in File
+	fileEncoded bool
+}
+
+// MarshalJSON encodes the document with its id as the API shows it, e.g. in
+// REST responses and change events
+func (d Document) MarshalJSON() ([]byte, error) {
+	type document Document
+	return json.Marshal(struct {
+		ID interface{} `json:"id"`
+		document
+	}{formatID(d.ID), document(d)})
+}
+
+// HasFile reports whether the document has a file, loaded or not
+func (d Document) HasFile() bool {
+	return d.File != "" || d.BlobRef != ""
+}
+
+// setFile replaces the file of the document
+func (d *Document) setFile(file string) {
+	d.File = file
+	d.BlobRef = ""
+}
+
+// FileSize returns the size in bytes of the decoded file content
+func (d Document) FileSize() int {
+	data, _ := decodeFile(d.File)
+	return len(data)
+}
+
+// humanSize formats a size in bytes for people, e.g. "1.2 MB". Units are
+// powers of 1024.
+func humanSize(size int) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	const units = "KMGTPE"
+	value := float64(size) / 1024
+	unit := 0
+	// move up a unit rather than print "1024.0 KB"
+	for value >= 1023.95 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %cB", value, units[unit])
+}
+
+// detectContentType guesses the media type of file content, falling back to
+// -default-content-type. Empty files have no content type.
+func detectContentType(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	// DetectContentType reports unknown content as octet-stream
+	if detected := http.DetectContentType(data); detected != "application/octet-stream" {
+		return detected
+	}
+	return *defaultContentType
+}
+
+// inferContentType returns the media type registered for the extension of
+// a document name, "" if it has none or it is unknown
+func inferContentType(name string) string {
+	extension := path.Ext(name)
+	if extension == "" {
+		return ""
+	}
+	return mime.TypeByExtension(extension)
+}
+
+// decodeFile returns the content of a base64 encoded file and whether it was
+// encoded. Files that are not valid base64 are taken as is.
+func decodeFile(file string) ([]byte, bool) {
+	data, err := base64.StdEncoding.Strict().DecodeString(file)
+	if err != nil {
+		return []byte(file), false
+	}
+	return data, true
+}
+
+// encodeFile is the reverse of decodeFile
+func encodeFile(data []byte, encoded bool) string {
+	if !encoded {
+		return string(data)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+var seedDocuments = []Document{
+	{
+		ID:   1,
+		Name: "Document one",
+		File: "SGVsbG8sIFdvcmxkIQ==",
+	},
+	{
+		ID:   2,
+		Name: "Document 2",
+		File: "SGVsbG8sIFdvcmxkIQ==",
+	},
+	{
+		ID:   3,
+		Name: "Document 3",
+		File: "SGVsbG8sIFdvcmxkIQ==",
+	},
+}
+
+// seedDocumentsFrom returns the seed documents numbered from base
+func seedDocumentsFrom(base int64) []Document {
+	documents := make([]Document, len(seedDocuments))
+	for i, document := range seedDocuments {
+		document.ID = base + int64(i)
+		documents[i] = document
+	}
+	return documents
+}
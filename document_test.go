@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeedIDBase(t *testing.T) {
+	seeds := seedDocumentsFrom(100)
+	for i, document := range seeds {
+		if document.ID != int64(100+i) {
+			t.Errorf("seed %d has id %d, want %d", i, document.ID, 100+i)
+		}
+	}
+	// created documents are numbered after the seeds
+	newTestStore(t, seeds...)
+	created, err := store.Create(context.Background(), Document{Name: "Report"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if want := int64(100 + len(seeds)); created.ID != want {
+		t.Errorf("created id %d, want %d", created.ID, want)
+	}
+	// the seeds themselves are not changed
+	if seedDocuments[0].ID != 1 {
+		t.Errorf("seedDocuments[0].ID = %d, want 1", seedDocuments[0].ID)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	for size, want := range map[int]string{
+		0:                      "0 B",
+		1:                      "1 B",
+		1023:                   "1023 B",
+		1024:                   "1.0 KB",
+		1536:                   "1.5 KB",
+		1024*1024 - 1:          "1.0 MB",
+		1024 * 1024:            "1.0 MB",
+		5*1024*1024 + 1024:     "5.0 MB",
+		3 * 1024 * 1024 * 1024: "3.0 GB",
+	} {
+		if got := humanSize(size); got != want {
+			t.Errorf("humanSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestFileSizeHuman(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Empty"}, testDocuments(2)[1])
+	data := mustExecute(t, newTestSchema(t), "{a:document(id:1){fileSizeHuman} b:document(id:2){fileSizeHuman}}", nil)
+	if got := lookup(data, "a", "fileSizeHuman"); got != "0 B" {
+		t.Errorf("fileSizeHuman of an empty file = %v, want 0 B", got)
+	}
+	if got := lookup(data, "b", "fileSizeHuman"); got != "13 B" {
+		t.Errorf("fileSizeHuman = %v, want 13 B", got)
+	}
+}
+
+func TestDefaultContentType(t *testing.T) {
+	setVar(t, defaultContentType, "application/x-unknown")
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	// bytes 0, 1, 2 and 255, which http.DetectContentType can't tell
+	data := mustExecute(t, schema, `mutation{create(name:"Binary",file:"AAEC/w=="){document{contentType}}}`, nil)
+	if got := lookup(data, "create", "document", "contentType"); got != "application/x-unknown" {
+		t.Errorf("created content type %v, want the default", got)
+	}
+	data = mustExecute(t, schema, `mutation{update(id:1,file:"AAEC/w=="){document{contentType}}}`, nil)
+	if got := lookup(data, "update", "document", "contentType"); got != "application/x-unknown" {
+		t.Errorf("updated content type %v, want the default", got)
+	}
+	// detected types are kept
+	data = mustExecute(t, schema, `mutation{create(name:"Text",file:"SGVsbG8="){document{contentType}}}`, nil)
+	if got := lookup(data, "create", "document", "contentType"); got != "text/plain; charset=utf-8" {
+		t.Errorf("text content type %v, want text/plain", got)
+	}
+}
+
+func TestInferContentTypes(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "report.pdf"},
+		Document{ID: 2, Name: "diagram.PNG"},
+		Document{ID: 3, Name: "notes.unknownext"},
+		Document{ID: 4, Name: "README"},
+		Document{ID: 5, Name: "scan.png", ContentType: "image/jpeg"},
+	)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{inferContentTypes{count}}`, nil)
+	if count := lookup(data, "inferContentTypes", "count"); count != 2.0 {
+		t.Errorf("count = %v, want 2", count)
+	}
+	documents := mustExecute(t, schema, "{list{id,contentType}}", nil)["list"].([]interface{})
+	// unknown extensions are skipped and set types kept
+	want := []string{"application/pdf", "image/png", "", "", "image/jpeg"}
+	for i, document := range documents {
+		if got := lookup(document, "contentType"); got != want[i] {
+			t.Errorf("content type of document %v = %q, want %q", lookup(document, "id"), got, want[i])
+		}
+	}
+}
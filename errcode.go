@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// resolverError returns the error returned by the resolver of the field
+// formatted failed on, or nil if it isn't the error of a field
+func resolverError(formatted gqlerrors.FormattedError) error {
+	// resolver errors come located in the query
+	if located, ok := formatted.OriginalError().(*gqlerrors.Error); ok {
+		// thunks, like those of batched document loads, fail with their
+		// error already formatted
+		if thunk, ok := located.OriginalError.(gqlerrors.FormattedError); ok {
+			return thunk.OriginalError()
+		}
+		return located.OriginalError
+	}
+	return nil
+}
+
+// errorCode returns the code of an error returned by a resolver, given to
+// clients in extensions.code along with the path of the field that failed
+func errorCode(err error) string {
+	var internal *internalError
+	switch {
+	case errors.Is(err, errNotFound):
+		return "notFound"
+	case errors.Is(err, errUnavailable), errors.Is(err, errBusy):
+		return "unavailable"
+	case errors.As(err, &internal):
+		return "internal"
+	}
+	return "badRequest"
+}
+
+// addErrorCodes sets extensions.code on the errors lacking one. Errors not
+// raised by a resolver are those of queries failing to parse or validate.
+func addErrorCodes(errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i, formatted := range errs {
+		if _, ok := formatted.Extensions["code"]; ok {
+			continue
+		}
+		code := "invalidQuery"
+		if err := resolverError(formatted); err != nil {
+			code = errorCode(err)
+		}
+		extensions := map[string]interface{}{"code": code}
+		for key, value := range formatted.Extensions {
+			extensions[key] = value
+		}
+		errs[i].Extensions = extensions
+	}
+	return errs
+}
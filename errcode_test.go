@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestErrorPathsAndCodes(t *testing.T) {
+	blobStore, err := newFSBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFSBlobStore: %v", err)
+	}
+	setVar(t, &blobs, BlobStore(blobStore))
+	// the blob of the file is missing
+	newTestStore(t, Document{ID: 1, Name: "Broken", BlobRef: "missing"})
+	schema := newTestSchema(t)
+
+	for _, test := range []struct {
+		query, path, code string
+	}{
+		{`mutation{update(id:99,name:"New"){document{id}}}`, "[update]", "notFound"},
+		{`{document(id:1){name,file}}`, "[document file]", "internal"},
+		{`{list{id,file}}`, "[list 0 file]", "internal"},
+		{`{document(id:1){nope}}`, "[]", "invalidQuery"},
+	} {
+		result := execute(context.Background(), schema, test.query, nil)
+		if len(result.Errors) != 1 {
+			t.Errorf("%s: errors %v, want one", test.query, result.Errors)
+			continue
+		}
+		if got := fmt.Sprint(result.Errors[0].Path); got != test.path {
+			t.Errorf("%s: path %s, want %s", test.query, got, test.path)
+		}
+		if got := result.Errors[0].Extensions["code"]; got != test.code {
+			t.Errorf("%s: code %v, want %s", test.query, got, test.code)
+		}
+	}
+	// the other fields of the document still resolve
+	result := execute(context.Background(), schema, `{document(id:1){name,file}}`, nil)
+	if got := lookup(jsonValue(t, result.Data), "document", "name"); got != "Broken" {
+		t.Errorf("name %v next to the failed file, want Broken", got)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// changeEvent describes a successful mutation of a document
+type changeEvent struct {
+	Type     string    `json:"type"` // name of the mutation
+	Document Document  `json:"document"`
+	Time     time.Time `json:"time"`
+}
+
+// publishEvent tells the listeners of document changes about event
+func publishEvent(event changeEvent) {
+	counters.count(event)
+	if queries != nil {
+		queries.changed()
+	}
+	if changes != nil {
+		changes.add(event)
+	}
+	notifyWebhooks(event)
+}
+
+// withChangeEvents publishes an event when resolve changes a document
+func withChangeEvents(operation string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		result, err := resolve(params)
+		if err != nil {
+			return result, err
+		}
+		// mutations of missing documents have no document to report
+		if payload, ok := result.(mutationPayload); ok {
+			if document, ok := payload.Document.(Document); ok && document.ID != 0 {
+				publishEvent(changeEvent{Type: operation, Document: document, Time: time.Now()})
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportHandler writes the documents as CSV, one row per document. The file
+// column is left out unless ?includeFile=true is set.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	includeFile, _ := strconv.ParseBool(r.URL.Query().Get("includeFile"))
+	if includeFile && !canReadFiles(r.Context()) {
+		http.Error(w, "the token lacks the scope to read files", http.StatusForbidden)
+		return
+	}
+	documents, err := store.List(r.Context())
+	if err != nil {
+		message, _ := safeMessage(r.Context(), err)
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="documents.csv"`)
+	cw := csv.NewWriter(w)
+	header := []string{"id", "name", "contentType", "createdAt"}
+	if includeFile {
+		header = append(header, "file")
+	}
+	cw.Write(header)
+	for _, document := range documents {
+		row := []string{
+			idString(document.ID),
+			document.Name,
+			document.ContentType,
+			document.CreatedAt.Format(time.RFC3339),
+		}
+		if includeFile {
+			withFileLoaded, err := withFile(document)
+			if err != nil {
+				// the response has started, end it short of the failed row
+				log.Printf("export: %v", err)
+				break
+			}
+			row = append(row, withFileLoaded.File)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	newTestStore(t, Document{
+		ID:          1,
+		Name:        `Report, "final"`,
+		File:        "SGVsbG8sIFdvcmxkIQ==",
+		ContentType: "text/plain",
+		CreatedAt:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	w := serve(http.HandlerFunc(exportHandler), httptest.NewRequest(http.MethodGet, "/document/export.csv", nil))
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	want := "id,name,contentType,createdAt\n" +
+		`1,"Report, ""final""",text/plain,2021-01-01T00:00:00Z` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("export = %q, want %q", got, want)
+	}
+
+	w = serve(http.HandlerFunc(exportHandler), httptest.NewRequest(http.MethodGet, "/document/export.csv?includeFile=true", nil))
+	want = "id,name,contentType,createdAt,file\n" +
+		`1,"Report, ""final""",text/plain,2021-01-01T00:00:00Z,SGVsbG8sIFdvcmxkIQ==` + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("export with files = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// fileHandler serves the decoded file of a document. http.ServeContent
+// handles Range requests, so downloads can be resumed.
+func fileHandler(w http.ResponseWriter, r *http.Request) {
+	if !canReadFiles(r.Context()) {
+		http.Error(w, "the token lacks the scope to read files", http.StatusForbidden)
+		return
+	}
+	id, err := parseIDString(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid document id", http.StatusBadRequest)
+		return
+	}
+	document, err := store.Get(r.Context(), id)
+	if errors.Is(err, errNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err == nil {
+		document, err = withFile(document)
+	}
+	if err != nil {
+		message, _ := safeMessage(r.Context(), err)
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+	data, _ := decodeFile(document.File)
+	w.Header().Set("Content-Disposition", contentDisposition(document.Name))
+	http.ServeContent(w, r, document.Name, document.UpdatedAt, bytes.NewReader(data))
+}
+
+// contentDisposition returns the Content-Disposition header downloading a
+// file called name. Names can have any character, so the name is given
+// percent-encoded in filename* (RFC 5987), with an ASCII approximation in
+// filename for clients not supporting it.
+func contentDisposition(name string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range name {
+		if r == '"' || r == '\\' || r < ' ' || r > '~' {
+			r = '_'
+		}
+		fallback.WriteRune(r)
+	}
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback.String(), encoded.String())
+}
+
+// isAttrChar reports whether b may appear unencoded in an RFC 5987 value
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// fileVerification is the result of checking the hash of a file
+type fileVerification struct {
+	Matches bool   `json:"matches"`
+	SHA256  string `json:"sha256"`
+}
+
+// verifyFile compares the hex SHA-256 of the decoded file of document with
+// sum, ignoring case. Documents without a file have the hash of no data.
+func verifyFile(document Document, sum string) (fileVerification, error) {
+	document, err := withFile(document)
+	if err != nil {
+		return fileVerification{}, err
+	}
+	data, _ := decodeFile(document.File)
+	hash := sha256.Sum256(data)
+	actual := hex.EncodeToString(hash[:])
+	return fileVerification{
+		Matches: subtle.ConstantTimeCompare([]byte(actual), []byte(strings.ToLower(sum))) == 1,
+		SHA256:  actual,
+	}, nil
+}
+
+// fileDataURI returns the file of document as a base64 data URI, for
+// embedding it in a page, or "" if the file is empty. Files without a content
+// type are given as application/octet-stream.
+func fileDataURI(document Document) (string, error) {
+	document, err := withFile(document)
+	if err != nil {
+		return "", err
+	}
+	data, _ := decodeFile(document.File)
+	if len(data) == 0 {
+		return "", nil
+	}
+	contentType := document.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// URIs can't have spaces, like the one after the ; of parameters
+	contentType = strings.ReplaceAll(contentType, " ", "")
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getFile requests the file of a document, with the headers of header
+func getFile(id string, header http.Header) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /document/{id}/file", fileHandler)
+	r := httptest.NewRequest(http.MethodGet, "/document/"+id+"/file", nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	return serve(mux, r)
+}
+
+func TestFileDownload(t *testing.T) {
+	documents := testDocuments(1)
+	documents[0].UpdatedAt = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newTestStore(t, documents...)
+
+	w := getFile("1", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "Hello, World!" {
+		t.Errorf("download: %d %q, want 200 Hello, World!", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+
+	w = getFile("1", http.Header{"Range": {"bytes=7-11"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "World" {
+		t.Errorf("range download: %d %q, want 206 World", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 7-11/13" {
+		t.Errorf("Content-Range = %q, want bytes 7-11/13", got)
+	}
+
+	// the modification time is the update time, for conditional requests
+	w = getFile("1", http.Header{"If-Modified-Since": {"Fri, 01 Jan 2021 00:00:00 GMT"}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional download: %d, want 304", w.Code)
+	}
+
+	if w := getFile("99", nil); w.Code != http.StatusNotFound {
+		t.Errorf("download of a missing document: %d, want 404", w.Code)
+	}
+	if w := getFile("one", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("download with an invalid id: %d, want 400", w.Code)
+	}
+}
+
+func TestFileDataURI(t *testing.T) {
+	for _, test := range []struct {
+		document Document
+		want     string
+	}{
+		{Document{File: "SGVsbG8sIFdvcmxkIQ=="}, "data:application/octet-stream;base64,SGVsbG8sIFdvcmxkIQ=="},
+		{Document{File: "SGVsbG8sIFdvcmxkIQ==", ContentType: "text/plain; charset=utf-8"}, "data:text/plain;charset=utf-8;base64,SGVsbG8sIFdvcmxkIQ=="},
+		// files which aren't base64 are taken as is, and encoded in the URI
+		{Document{File: "Hello", ContentType: "text/plain"}, "data:text/plain;base64,SGVsbG8="},
+		{Document{}, ""},
+	} {
+		got, err := fileDataURI(test.document)
+		if err != nil || got != test.want {
+			t.Errorf("fileDataURI(%+v) = %q, %v, want %q", test.document, got, err, test.want)
+		}
+	}
+}
+
+func TestSeedDocumentDataURI(t *testing.T) {
+	newTestStore(t, seedDocumentsFrom(1)...)
+	data := mustExecute(t, newTestSchema(t), "{document(id:1){fileDataUri}}", nil)
+	// as in the README
+	if got := lookup(data, "document", "fileDataUri"); got != "data:application/octet-stream;base64,SGVsbG8sIFdvcmxkIQ==" {
+		t.Errorf("fileDataUri of document 1 = %v", got)
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	const sum = "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f" // of "Hello, World!"
+	for _, test := range []struct {
+		sha256  string
+		matches bool
+	}{
+		{sum, true},
+		{strings.ToUpper(sum), true},
+		{strings.Repeat("0", 64), false},
+	} {
+		data := mustExecute(t, schema, `{verifyFile(id:1,sha256:"`+test.sha256+`"){matches,sha256}}`, nil)
+		if got := lookup(data, "verifyFile", "matches"); got != test.matches {
+			t.Errorf("%s: matches %v, want %v", test.sha256, got, test.matches)
+		}
+		if got := lookup(data, "verifyFile", "sha256"); got != sum {
+			t.Errorf("%s: sha256 %v, want %s", test.sha256, got, sum)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"report.pdf", `attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`},
+		{"Annual report.pdf", `attachment; filename="Annual report.pdf"; filename*=UTF-8''Annual%20report.pdf`},
+		{"Résumé \"final\".pdf", `attachment; filename="R_sum_ _final_.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9%20%22final%22.pdf`},
+		{"日本.txt", `attachment; filename="__.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC.txt`},
+	} {
+		if got := contentDisposition(test.name); got != test.want {
+			t.Errorf("%q: %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	newTestStore(t, Document{ID: 1, Name: "Été 2021.txt", File: "SGVsbG8="})
+	w := getFile("1", nil)
+	if _, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition")); err != nil || params["filename"] != "Été 2021.txt" {
+		t.Errorf("Content-Disposition %q: filename %q, %v, want the decoded name", w.Header().Get("Content-Disposition"), params["filename"], err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// documentFilter selects the documents returned by the list query and
+// changed by tagMatching. Zero fields don't filter.
+type documentFilter struct {
+	createdAfter  time.Time
+	createdBefore time.Time
+	hasFile       *bool
+	nameContains  string // lowercased
+}
+
+// newDocumentFilter reads the filter from the arguments of a field
+func newDocumentFilter(args map[string]interface{}) documentFilter {
+	f := documentFilter{}
+	nameContains, _ := args["nameContains"].(string)
+	f.nameContains = strings.ToLower(nameContains)
+	f.createdAfter, _ = args["createdAfter"].(time.Time)
+	f.createdBefore, _ = args["createdBefore"].(time.Time)
+	if hasFile, ok := args["hasFile"].(bool); ok {
+		f.hasFile = &hasFile
+	}
+	return f
+}
+
+// matches reports whether document passes the filter
+func (f documentFilter) matches(document Document) bool {
+	if !f.createdAfter.IsZero() && !document.CreatedAt.After(f.createdAfter) {
+		return false
+	}
+	if !f.createdBefore.IsZero() && !document.CreatedAt.Before(f.createdBefore) {
+		return false
+	}
+	if f.hasFile != nil && *f.hasFile != document.HasFile() {
+		return false
+	}
+	if f.nameContains != "" && !strings.Contains(strings.ToLower(document.Name), f.nameContains) {
+		return false
+	}
+	return true
+}
+
+// apply returns the documents that pass the filter
+func (f documentFilter) apply(documents []Document) []Document {
+	matching := []Document{}
+	for _, document := range documents {
+		if f.matches(document) {
+			matching = append(matching, document)
+		}
+	}
+	return matching
+}
+
+// paginate returns the page of documents selected by the limit and offset
+// arguments. Pages are at most -max-page-size long: larger limits are capped,
+// or rejected with -strict-page-size.
+func paginate(documents []Document, args map[string]interface{}) ([]Document, error) {
+	limit := *maxPageSize
+	if requested, ok := args["limit"].(int); ok {
+		if requested < 0 {
+			return nil, fmt.Errorf("limit must not be negative")
+		}
+		if requested > *maxPageSize && *strictPageSize {
+			return nil, fmt.Errorf("limit %d exceeds the maximum page size of %d", requested, *maxPageSize)
+		}
+		if requested < limit {
+			limit = requested
+		}
+	}
+	offset, _ := args["offset"].(int)
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+
+	if offset > len(documents) {
+		offset = len(documents)
+	}
+	documents = documents[offset:]
+	if limit < len(documents) {
+		documents = documents[:limit]
+	}
+	return documents, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// idsOf returns the ids of the documents of a list in a result
+func idsOf(list interface{}) string {
+	documents, _ := list.([]interface{})
+	ids := []interface{}{}
+	for _, document := range documents {
+		ids = append(ids, lookup(document, "id"))
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestCreatedBetween(t *testing.T) {
+	documents := testDocuments(4)
+	for i := range documents {
+		documents[i].CreatedAt = time.Date(2021, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC)
+	}
+	newTestStore(t, documents...)
+	schema := newTestSchema(t)
+	for _, test := range []struct {
+		args string
+		want string
+	}{
+		{`createdAfter:"2021-01-15T00:00:00Z"`, "[2 3 4]"},
+		{`createdBefore:"2021-03-01T00:00:00Z"`, "[1 2]"},
+		{`createdAfter:"2021-01-15T00:00:00Z",createdBefore:"2021-04-01T00:00:00Z"`, "[2 3]"},
+		// both bounds are exclusive
+		{`createdAfter:"2021-02-01T00:00:00Z",createdBefore:"2021-03-01T00:00:00Z"`, "[]"},
+		{`createdAfter:"2021-01-15T00:00:00Z",hasFile:false`, "[]"},
+	} {
+		query := "{list(" + test.args + "){id}}"
+		if got := idsOf(mustExecute(t, schema, query, nil)["list"]); got != test.want {
+			t.Errorf("%s = %s, want %s", query, got, test.want)
+		}
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	setVar(t, maxPageSize, 3)
+	newTestStore(t, testDocuments(5)...)
+	schema := newTestSchema(t)
+	for _, test := range []struct {
+		query string
+		want  string
+	}{
+		{"{list{id}}", "[1 2 3]"},
+		{"{list(limit:10){id}}", "[1 2 3]"},
+		{"{list(limit:2,offset:1){id}}", "[2 3]"},
+		{"{list(offset:4){id}}", "[5]"},
+	} {
+		if got := idsOf(mustExecute(t, schema, test.query, nil)["list"]); got != test.want {
+			t.Errorf("%s = %s, want %s", test.query, got, test.want)
+		}
+	}
+
+	setVar(t, strictPageSize, true)
+	result := execute(context.Background(), schema, "{list(limit:10){id}}", nil)
+	if !result.HasErrors() || result.Errors[0].Message != "limit 10 exceeds the maximum page size of 3" {
+		t.Errorf("limit above the maximum with -strict-page-size: %v", result.Errors)
+	}
+	if got := idsOf(mustExecute(t, schema, "{list(limit:3){id}}", nil)["list"]); got != "[1 2 3]" {
+		t.Errorf("limit at the maximum with -strict-page-size = %s", got)
+	}
+}
+
+func TestHasFile(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "With a file", File: "SGVsbG8="},
+		Document{ID: 2, Name: "Without a file"},
+		Document{ID: 3, Name: "With a file too", File: "SGVsbG8="},
+	)
+	schema := newTestSchema(t)
+	for query, want := range map[string]string{
+		"{list(hasFile:true){id}}":  "[1 3]",
+		"{list(hasFile:false){id}}": "[2]",
+		"{list{id}}":                "[1 2 3]",
+	} {
+		if got := idsOf(mustExecute(t, schema, query, nil)["list"]); got != want {
+			t.Errorf("%s = %s, want %s", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/christallization/go-graphql-crud
+
+go 1.26.0
+
+require (
+	github.com/graphql-go/graphql v0.8.1
+	golang.org/x/sync v0.23.0
+	golang.org/x/text v0.42.0
+)
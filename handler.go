@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// graphqlRequest contains the query and its parameters sent by a client
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	// path is the endpoint the request was sent to, which selects the schema
+	path string
+	// readsFiles is whether the client may read files, so it doesn't share
+	// cached results with clients who may not
+	readsFiles bool
+	// document is the parsed query, shared by the checks of the handler
+	// and the execution, or parseErr why it doesn't parse
+	document *ast.Document
+	parseErr error
+	// validated is set for documents derived from an already validated
+	// one, like those resolving the rest of a streamed list
+	validated bool
+}
+
+// parse parses the query of req, unless it already is
+func (req *graphqlRequest) parse() {
+	if req.document != nil || req.parseErr != nil {
+		return
+	}
+	req.document, req.parseErr = parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+}
+
+// parseRequest reads the GraphQL request from the URL of a GET request, with
+// variables JSON encoded, or from the body of a POST request. POST bodies
+// are either JSON encoded or, with the application/graphql content type, the
+// raw query string.
+func parseRequest(r *http.Request) (graphqlRequest, error) {
+	req := graphqlRequest{}
+	if r.Method != http.MethodPost {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := decodeJSON(strings.NewReader(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("invalid variables: %v", err)
+			}
+			req.Variables = exactNumbers(req.Variables).(map[string]interface{})
+		}
+		return req, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/graphql" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return req, err
+		}
+		req.Query = string(body)
+		return req, nil
+	}
+
+	if err := decodeJSON(r.Body, &req); err != nil {
+		return req, err
+	}
+	req.Variables = exactNumbers(req.Variables).(map[string]interface{})
+	return req, nil
+}
+
+// decodeJSON decodes the JSON value read from r into v, keeping numbers as
+// json.Number rather than float64, which can't hold every int64
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// exactNumbers replaces the json.Numbers of a value decoded by decodeJSON
+// with int64s for integers and float64s for other numbers, the types
+// GraphQL coerces arguments from. Integers keep every digit, so an id too
+// large for an Int is rejected rather than rounded to another document.
+func exactNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = exactNumbers(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = exactNumbers(v)
+		}
+	}
+	return value
+}
+
+// selectOperation returns the operation of document selected by
+// operationName, or the first one if operationName is empty. It returns nil
+// if the query didn't parse, when document is nil.
+func selectOperation(document *ast.Document, operationName string) *ast.OperationDefinition {
+	if document == nil {
+		return nil
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation
+		}
+	}
+	return nil
+}
+
+// operationType returns the type (query, mutation or subscription) of the
+// operation selected by operationName. It returns "" if the query didn't
+// parse, leaving the error to be reported by runQuery.
+func operationType(document *ast.Document, operationName string) string {
+	if operation := selectOperation(document, operationName); operation != nil {
+		return operation.Operation
+	}
+	return ""
+}
+
+// graphqlResponseMediaType is the media type of GraphQL responses defined by
+// the GraphQL over HTTP spec
+const graphqlResponseMediaType = "application/graphql-response+json"
+
+// responseMediaType returns the media type of the response to r, from its
+// Accept header: the JSON media type accepted with the highest q, the first
+// listed of equal ones, or application/json
+func responseMediaType(r *http.Request) string {
+	best, bestQ := "application/json", 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if mediaType != graphqlResponseMediaType && mediaType != "application/json" {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// resultStatus returns the status of the response with result. Clients
+// accepting the GraphQL response media type are told of requests failing
+// before execution, having no data, with a 400; application/json responses
+// are always 200.
+func resultStatus(r *http.Request, result *graphql.Result) int {
+	if responseMediaType(r) == graphqlResponseMediaType && result.Data == nil && result.HasErrors() {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// writeJSON writes v as the JSON response, indented if the client asked for
+// it with ?pretty=true or pretty printing is on by default
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	pretty := *prettyDefault
+	if value := r.URL.Query().Get("pretty"); value != "" {
+		pretty, _ = strconv.ParseBool(value)
+	}
+	var body []byte
+	var err error
+	if pretty {
+		body, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", responseMediaType(r))
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// writeError writes a GraphQL response with an error and no data
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, r, status, graphql.Result{
+		Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)},
+	})
+}
+
+// writeBudgetExceeded rejects a query costing cost for lack of budget, with
+// the time from which it can be retried if there is one
+func writeBudgetExceeded(w http.ResponseWriter, r *http.Request, cost int, resetAt time.Time) {
+	formatted := gqlerrors.NewFormattedError("rate limit exceeded")
+	formatted.Extensions = map[string]interface{}{"code": "rateLimited", "cost": cost}
+	if resetAt.IsZero() {
+		formatted.Message = fmt.Sprintf("rate limit exceeded: the query costs %d, more than the budget of %d", cost, *userCostBudget)
+	} else {
+		formatted.Extensions["resetAt"] = resetAt.UTC().Format(time.RFC3339)
+		retryAfter := int(math.Ceil(time.Until(resetAt).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	writeJSON(w, r, http.StatusTooManyRequests, graphql.Result{Errors: []gqlerrors.FormattedError{formatted}})
+}
+
+// formatResult shapes result as configured by -response-format. The rest
+// format responds with the value of the only field of the data, falling
+// back to the spec shape when there are errors or several fields.
+func formatResult(result *graphql.Result) interface{} {
+	if *responseFormat != "rest" || result.HasErrors() {
+		return result
+	}
+	data, ok := result.Data.(map[string]interface{})
+	if !ok || len(data) != 1 {
+		return result
+	}
+	for _, value := range data {
+		return value
+	}
+	return result
+}
+
+// copyResult returns a copy of result whose extensions can be changed
+func copyResult(result *graphql.Result) *graphql.Result {
+	copied := *result
+	copied.Extensions = map[string]interface{}{}
+	for key, value := range result.Extensions {
+		copied.Extensions[key] = value
+	}
+	return &copied
+}
+
+// documentHandler executes the GraphQL requests sent to /document
+func documentHandler(schema graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// whatever the query, the client gets a JSON response
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("request %s: panic: %v\n%s", requestIDFrom(r.Context()), v, debug.Stack())
+				writeError(w, r, http.StatusInternalServerError, "internal error")
+			}
+		}()
+		req, err := parseRequest(r)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.path = r.URL.Path
+		req.readsFiles = canReadFiles(r.Context())
+		if strings.TrimSpace(req.Query) == "" {
+			writeError(w, r, http.StatusBadRequest, "no query provided")
+			return
+		}
+		// checked before anything parses the query
+		if *maxQueryLength > 0 && len(req.Query) > *maxQueryLength {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("query is longer than %d bytes", *maxQueryLength))
+			return
+		}
+		req.parse()
+		if *logQueries {
+			logQuery(r.Context(), req)
+		}
+		if anonymous(r) && !introspectionOnly(req.document, req.OperationName) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if user := userFrom(r.Context()); budgets != nil && user != "" {
+			if cost, ok := queryCost(schema, req.document, req.OperationName); ok {
+				if ok, resetAt := budgets.spend(user, cost, time.Now()); !ok {
+					writeBudgetExceeded(w, r, cost, resetAt)
+					return
+				}
+			}
+		}
+		if operationType(req.document, req.OperationName) == ast.OperationTypeMutation {
+			if *readOnly {
+				writeError(w, r, http.StatusForbidden, "mutations disabled")
+				return
+			}
+			// GET must be safe, and may be cached or prefetched
+			if r.Method == http.MethodGet {
+				w.Header().Set("Allow", http.MethodPost)
+				writeError(w, r, http.StatusMethodNotAllowed, "mutations must be sent with POST")
+				return
+			}
+			if mutations != nil {
+				if err := mutations.acquire(r.Context()); err != nil {
+					w.Header().Set("Retry-After", "1")
+					writeError(w, r, http.StatusServiceUnavailable, err.Error())
+					return
+				}
+				defer mutations.release()
+			}
+		}
+		if stream, ok := streamedList(req); ok && acceptsMultipart(r) {
+			writeStream(w, r, req, schema, stream)
+			return
+		}
+		result := executeQuery(r.Context(), req, schema)
+		if id := requestIDFrom(r.Context()); id != "" {
+			// results can be shared through the cache, the id goes on a copy
+			result = copyResult(result)
+			setExtension(result, "requestId", id)
+		}
+		writeJSON(w, r, resultStatus(r, result), formatResult(result))
+	}
+}
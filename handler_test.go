@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// FuzzHandler feeds queries to the document handler, which must answer all
+// of them with JSON and without panicking. Seeds are in
+// testdata/fuzz/FuzzHandler.
+func FuzzHandler(f *testing.F) {
+	f.Add("{document(id:1){id,name,file}}")
+	newTestStore(f, testDocuments(3)...)
+	handler := documentHandler(newTestSchema(f))
+	f.Fuzz(func(t *testing.T, query string) {
+		w := postQuery(handler, query)
+		// the handler only fails with a 500 when it recovers from a panic
+		if w.Code == http.StatusInternalServerError {
+			t.Fatalf("query %q: status 500: %s", query, w.Body.String())
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Fatalf("query %q: invalid JSON response %q", query, w.Body.String())
+		}
+	})
+}
+
+func TestLargeIDVariable(t *testing.T) {
+	newTestStore(t, Document{ID: 2147483647, Name: "Last"})
+	handler := documentHandler(newTestSchema(t))
+	post := func(id string) map[string]interface{} {
+		body := `{"query":"query($id:Int!){document(id:$id){id,name}}","variables":{"id":` + id + `}}`
+		r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return decodeResponse(t, serve(handler, r))
+	}
+	if got := lookup(post("2147483647"), "data", "document", "name"); got != "Last" {
+		t.Errorf("document 2147483647 = %v, want Last", got)
+	}
+	// 2^53+1 rounds to 2^53 as a float64, and must be rejected rather
+	// than read as another id
+	if response := post("9007199254740993"); response["errors"] == nil || lookup(response, "data", "document") != nil {
+		t.Errorf("document 9007199254740993 = %v, want an error", response)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	newTestStore(t)
+	errors, _ := decodeResponse(t, postQuery(documentHandler(newTestSchema(t)), "{list{id}"))["errors"].([]interface{})
+	if len(errors) != 1 {
+		t.Fatalf("errors %v, want a syntax error", errors)
+	}
+	if message, _ := lookup(errors[0], "message").(string); !strings.HasPrefix(message, "Syntax Error GraphQL request (1:10)") {
+		t.Errorf("error %q, want a syntax error at 1:10", message)
+	}
+}
+
+// postQuery posts a raw query to handler
+func postQuery(handler http.Handler, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(query))
+	r.Header.Set("Content-Type", "application/graphql")
+	return serve(handler, r)
+}
+
+func TestGraphQLContentType(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := documentHandler(newTestSchema(t))
+	// the body is the query, with the media type parameters ignored
+	r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(`{document(id:1){name}}`))
+	r.Header.Set("Content-Type", "application/graphql; charset=utf-8")
+	if got := lookup(decodeResponse(t, serve(handler, r)), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("name = %v, want Document 1", got)
+	}
+	// JSON bodies still work
+	r = httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(`{"query":"{document(id:1){name}}"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if got := lookup(decodeResponse(t, serve(handler, r)), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("name with a JSON body = %v, want Document 1", got)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	setVar(t, readOnly, true)
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	if schema.MutationType() != nil {
+		t.Error("the read-only schema has a Mutation type")
+	}
+	handler := documentHandler(schema)
+	w := postQuery(handler, `mutation{delete(id:1){deleted}}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("mutation status %d, want 403", w.Code)
+	}
+	if message := lookup(decodeResponse(t, w)["errors"].([]interface{})[0], "message"); message != "mutations disabled" {
+		t.Errorf("mutation error %v, want mutations disabled", message)
+	}
+	if got := lookup(decodeResponse(t, postQuery(handler, "{document(id:1){name}}")), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("query name = %v, want Document 1", got)
+	}
+}
+
+func TestEmptyQuery(t *testing.T) {
+	newTestStore(t)
+	handler := documentHandler(newTestSchema(t))
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/document", nil),
+		httptest.NewRequest(http.MethodGet, "/document?query=%20%0A", nil),
+		httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(`{"query":"  "}`)),
+	} {
+		w := serve(handler, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status %d, want 400", r.Method, r.URL, w.Code)
+		}
+		if message := lookup(decodeResponse(t, w)["errors"].([]interface{})[0], "message"); message != "no query provided" {
+			t.Errorf("%s %s: error %v, want no query provided", r.Method, r.URL, message)
+		}
+	}
+}
+
+func TestPretty(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := documentHandler(newTestSchema(t))
+	get := func(url string) string {
+		return serve(handler, httptest.NewRequest(http.MethodGet, url, nil)).Body.String()
+	}
+	if body := get("/document?query={document(id:1){id}}"); strings.Count(body, "\n") != 1 {
+		t.Errorf("compact response %q", body)
+	}
+	if body := get("/document?query={document(id:1){id}}&pretty=true"); !strings.Contains(body, "{\n  \"data\": {\n    \"document\"") {
+		t.Errorf("pretty response %q", body)
+	}
+
+	setVar(t, prettyDefault, true)
+	if body := get("/document?query={document(id:1){id}}"); !strings.Contains(body, "\n  ") {
+		t.Errorf("response %q, want it pretty by default", body)
+	}
+	if body := get("/document?query={document(id:1){id}}&pretty=false"); strings.Contains(body, "\n  ") {
+		t.Errorf("response %q with pretty=false", body)
+	}
+}
+
+func TestRunQueryParsesTheQuery(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	// requests which didn't go through the handler are parsed too
+	result := runQuery(context.Background(), graphqlRequest{Query: "{document(id:1){id}}"}, newTestSchema(t))
+	if result.HasErrors() || result.Extensions["cost"] != 2 {
+		t.Errorf("result %v, extensions %v, want data and a cost of 2", result.Data, result.Extensions)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := documentHandler(newTestSchema(t))
+	query := "{document(id:1){name}}"
+	if got := lookup(decodeResponse(t, postQuery(handler, query)), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("graphql format: name = %v, want Document 1", got)
+	}
+
+	setVar(t, responseFormat, "rest")
+	if got := decodeResponse(t, postQuery(handler, query)); len(got) != 1 || got["name"] != "Document 1" {
+		t.Errorf("rest format: %v, want the document only", got)
+	}
+	// several fields and errors keep the spec shape
+	if got := decodeResponse(t, postQuery(handler, "{a:document(id:1){name} b:document(id:1){name}}")); lookup(got, "data", "a", "name") != "Document 1" {
+		t.Errorf("rest format with two fields: %v, want the spec shape", got)
+	}
+	if got := decodeResponse(t, postQuery(handler, "{document(id:1){nope}}")); got["errors"] == nil {
+		t.Errorf("rest format with an error: %v, want the spec shape", got)
+	}
+}
+
+func TestMaxQueryLength(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, maxQueryLength, 30)
+	handler := documentHandler(newTestSchema(t))
+	query := "{document(id:1){id}}"
+	w := postQuery(handler, query+strings.Repeat(" ", 30-len(query)))
+	if w.Code != http.StatusOK || lookup(decodeResponse(t, w), "data", "document", "id") != 1.0 {
+		t.Errorf("query of the maximum length: %d %s", w.Code, w.Body.String())
+	}
+	// an over-length query is rejected before it is parsed, even if invalid
+	w = postQuery(handler, "{"+strings.Repeat("a", 30))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("over-length query: status %d, want 400", w.Code)
+	}
+	if message := lookup(decodeResponse(t, w)["errors"].([]interface{})[0], "message"); message != "query is longer than 30 bytes" {
+		t.Errorf("over-length query: error %v", message)
+	}
+}
+
+func TestGetMutation(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := documentHandler(newTestSchema(t))
+	get := func(query string) *httptest.ResponseRecorder {
+		return serve(handler, httptest.NewRequest(http.MethodGet, "/document?query="+url.QueryEscape(query), nil))
+	}
+	w := get(`mutation{delete(id:1){id}}`)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET mutation: status %d, Allow %q, want 405 and POST", w.Code, w.Header().Get("Allow"))
+	}
+	if got := lookup(decodeResponse(t, get(`{document(id:1){name}}`)), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("GET query: document %v, want Document 1", got)
+	}
+}
+
+func TestGetVariables(t *testing.T) {
+	newTestStore(t, testDocuments(2)...)
+	handler := documentHandler(newTestSchema(t))
+	get := func(variables string) *httptest.ResponseRecorder {
+		query := url.Values{"query": {`query($id:Int!){document(id:$id){name}}`}, "variables": {variables}}
+		return serve(handler, httptest.NewRequest(http.MethodGet, "/document?"+query.Encode(), nil))
+	}
+	if got := lookup(decodeResponse(t, get(`{"id":2}`)), "data", "document", "name"); got != "Document 2" {
+		t.Errorf("document %v, want Document 2", got)
+	}
+	if w := get(`{"id":`); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed variables: status %d, want 400", w.Code)
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := documentHandler(newTestSchema(t))
+	for _, test := range []struct {
+		accept, query string
+		wantType      string
+		wantStatus    int
+	}{
+		{"", "{document(id:1){name}}", "application/json", http.StatusOK},
+		{"", "{document(id:1){name}", "application/json", http.StatusOK},
+		{"application/json", "{document(id:1){name}}", "application/json", http.StatusOK},
+		{"application/json", "{document(id:1){name}", "application/json", http.StatusOK},
+		{graphqlResponseMediaType, "{document(id:1){name}}", graphqlResponseMediaType, http.StatusOK},
+		{graphqlResponseMediaType, "{document(id:1){name}", graphqlResponseMediaType, http.StatusBadRequest},
+		{graphqlResponseMediaType + ", application/json;q=0.9", "{document(id:1){name}}", graphqlResponseMediaType, http.StatusOK},
+		{"application/json;q=0.5, " + graphqlResponseMediaType, "{document(id:1){name}}", graphqlResponseMediaType, http.StatusOK},
+		{graphqlResponseMediaType + ";q=0.5, application/json", "{document(id:1){name}", "application/json", http.StatusOK},
+		{"application/json, " + graphqlResponseMediaType, "{document(id:1){name}", "application/json", http.StatusOK},
+		{graphqlResponseMediaType + ";q=0, application/json;q=0.1", "{document(id:1){name}", "application/json", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(test.query))
+		r.Header.Set("Content-Type", "application/graphql")
+		r.Header.Set("Accept", test.accept)
+		w := serve(handler, r)
+		if got := w.Header().Get("Content-Type"); got != test.wantType || w.Code != test.wantStatus {
+			t.Errorf("Accept %q, query %q: %s with status %d, want %s with %d", test.accept, test.query, got, w.Code, test.wantType, test.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// ready is set once startup has completed and the store can serve requests
+var ready atomic.Bool
+
+// healthzHandler reports that the server is alive
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler reports whether the server is ready to serve requests
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if !ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// whenReady answers requests with a 503 until the server is ready, so
+// clients don't reach the store while it is set up
+func whenReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyz(t *testing.T) {
+	defer ready.Store(ready.Load())
+	ready.Store(false)
+	handler := http.HandlerFunc(readyzHandler)
+	if w := serve(handler, httptest.NewRequest(http.MethodGet, "/readyz", nil)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status before ready = %d, want 503", w.Code)
+	}
+	ready.Store(true)
+	if w := serve(handler, httptest.NewRequest(http.MethodGet, "/readyz", nil)); w.Code != http.StatusOK {
+		t.Errorf("status once ready = %d, want 200", w.Code)
+	}
+}
+
+func TestWhenReady(t *testing.T) {
+	defer ready.Store(ready.Load())
+	ready.Store(false)
+	handler := whenReady(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	w := serve(handler, httptest.NewRequest(http.MethodGet, "/document", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("before ready: status %d, Retry-After %q, want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	ready.Store(true)
+	if w := serve(handler, httptest.NewRequest(http.MethodGet, "/document", nil)); w.Code != http.StatusOK {
+		t.Errorf("status once ready = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// MutationHook is called before a mutation runs, with the name of the
+// mutation and its arguments. Returning an error aborts the mutation and
+// reports the error to the client.
+type MutationHook func(ctx context.Context, operation string, args map[string]interface{}) error
+
+var (
+	mutationHooksMu sync.RWMutex
+	mutationHooks   []MutationHook
+)
+
+// RegisterMutationHook adds a hook run before every mutation, after the hooks
+// registered before it. Hooks are typically registered from an init function
+// in a file added to this package.
+func RegisterMutationHook(hook MutationHook) {
+	mutationHooksMu.Lock()
+	defer mutationHooksMu.Unlock()
+	mutationHooks = append(mutationHooks, hook)
+}
+
+// runMutationHooks runs the registered hooks in order, stopping at the first
+// error
+func runMutationHooks(ctx context.Context, operation string, args map[string]interface{}) error {
+	mutationHooksMu.RLock()
+	hooks := mutationHooks
+	mutationHooksMu.RUnlock()
+	for _, hook := range hooks {
+		if err := hook(ctx, operation, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withMutationHooks runs the registered hooks before resolve
+func withMutationHooks(operation string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		if err := runMutationHooks(params.Context, operation, params.Args); err != nil {
+			return nil, err
+		}
+		return resolve(params)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMutationHooks(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &mutationHooks, nil)
+	var operations []string
+	RegisterMutationHook(func(ctx context.Context, operation string, args map[string]interface{}) error {
+		operations = append(operations, operation)
+		return nil
+	})
+	RegisterMutationHook(func(ctx context.Context, operation string, args map[string]interface{}) error {
+		if name, _ := args["name"].(string); strings.HasPrefix(name, "tmp") {
+			return errors.New("temporary documents are not allowed")
+		}
+		return nil
+	})
+	schema := newTestSchema(t)
+
+	for _, query := range []string{
+		`mutation{create(name:"tmp report"){document{id}}}`,
+		`mutation{update(id:1,name:"tmp report"){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() || result.Errors[0].Message != "temporary documents are not allowed" {
+			t.Errorf("%s: errors %v, want the error of the hook", query, result.Errors)
+		}
+	}
+	if name := lookup(mustExecute(t, schema, "{document(id:1){name}}", nil), "document", "name"); name != "Document 1" {
+		t.Errorf("name = %v after a rejected update", name)
+	}
+	mustExecute(t, schema, `mutation{create(name:"Report"){document{id}}}`, nil)
+	mustExecute(t, schema, `mutation{delete(id:1){deleted}}`, nil)
+	if got := strings.Join(operations, ","); got != "create,update,create,delete" {
+		t.Errorf("hooks ran for %s", got)
+	}
+}
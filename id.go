@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// idStrategy is how the store picks the ids of created documents
+type idStrategy string
+
+const (
+	// sequentialIDs numbers documents 1, 2, 3...
+	sequentialIDs idStrategy = "sequential"
+	// randomIDs picks unused random ids, so ids don't reveal how many
+	// documents were created
+	randomIDs idStrategy = "random"
+	// uuidIDs picks unused random 62-bit ids, which the API shows as UUIDs
+	// in GraphQL IDs (strings) rather than as Ints
+	uuidIDs idStrategy = "uuid"
+)
+
+// parseIDStrategy returns the strategy named by the -id-strategy flag
+func parseIDStrategy(name string) (idStrategy, error) {
+	switch strategy := idStrategy(name); strategy {
+	case sequentialIDs, randomIDs, uuidIDs:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown id strategy %q, expected sequential, random or uuid", name)
+	}
+}
+
+// advanceID picks the id of the next created document. s.mu must be held.
+func (s *memoryStore) advanceID() {
+	if s.ids != randomIDs && s.ids != uuidIDs {
+		s.nextID++
+		return
+	}
+	// GraphQL Ints are 32-bit, UUIDs hold 62 bits of id
+	limit := int64(math.MaxInt32)
+	if s.ids == uuidIDs {
+		limit = 1<<62 - 1
+	}
+	for {
+		id := randomInt63n(limit) + 1
+		if s.find(id) < 0 {
+			s.nextID = id
+			return
+		}
+	}
+}
+
+// uuidMode reports whether the API shows ids as UUIDs
+func uuidMode() bool {
+	return idStrategy(*idStrategyName) == uuidIDs
+}
+
+// documentIDType is the GraphQL type of document ids: Int, or ID in uuid
+// mode
+func documentIDType() *graphql.Scalar {
+	if uuidMode() {
+		return graphql.ID
+	}
+	return graphql.Int
+}
+
+// formatID returns a document id as the API shows it: the number, or its
+// UUID in uuid mode
+func formatID(id int64) interface{} {
+	if uuidMode() {
+		return uuidOf(id)
+	}
+	return id
+}
+
+// idString is formatID as a string, for paths and messages
+func idString(id int64) string {
+	return fmt.Sprint(formatID(id))
+}
+
+// documentID reads a document id given as a GraphQL argument
+func documentID(value interface{}) (int64, error) {
+	if !uuidMode() {
+		id, _ := value.(int)
+		return int64(id), nil
+	}
+	uuid, _ := value.(string)
+	return parseUUID(uuid)
+}
+
+// documentIDs reads a list of document ids given as a GraphQL argument
+func documentIDs(values []interface{}) ([]int64, error) {
+	ids := make([]int64, len(values))
+	for i, value := range values {
+		id, err := documentID(value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseIDString reads a document id given as text, e.g. in a path
+func parseIDString(text string) (int64, error) {
+	if uuidMode() {
+		return parseUUID(text)
+	}
+	id, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid document id %q", text)
+	}
+	return id, nil
+}
+
+// uuidOf returns the UUID showing id: a version 8 (custom) UUID whose last
+// 62 bits are the id, and whose other bits are taken from a hash of it, so
+// that mistyped UUIDs are rejected rather than read as another id
+func uuidOf(id int64) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[8:], uint64(id))
+	sum := sha256.Sum256(b[8:])
+	copy(b[:8], sum[:8])
+	b[6] = b[6]&0x0f | 0x80 // version 8
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant, ids are under 2^62
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// parseUUID is the reverse of uuidOf
+func parseUUID(uuid string) (int64, error) {
+	hexDigits := strings.ReplaceAll(uuid, "-", "")
+	if len(uuid) != 36 || len(hexDigits) != 32 {
+		return 0, fmt.Errorf("invalid document id %q, expected a UUID", uuid)
+	}
+	b, err := hex.DecodeString(hexDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid document id %q, expected a UUID", uuid)
+	}
+	id := int64(binary.BigEndian.Uint64(b[8:]) & (1<<62 - 1))
+	if uuidOf(id) != strings.ToLower(uuid) {
+		return 0, fmt.Errorf("invalid document id %q", uuid)
+	}
+	return id, nil
+}
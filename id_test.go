@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestIDStrategies(t *testing.T) {
+	for _, strategy := range []idStrategy{sequentialIDs, randomIDs, uuidIDs} {
+		t.Run(string(strategy), func(t *testing.T) {
+			memory, err := newMemoryStore(false, false, strategy, blobs, testDocuments(3))
+			if err != nil {
+				t.Fatalf("newMemoryStore: %v", err)
+			}
+			seen := map[int64]bool{1: true, 2: true, 3: true}
+			for i := 0; i < 100; i++ {
+				document, err := memory.Create(context.Background(), Document{Name: "Document"})
+				if err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+				if document.ID < 1 || seen[document.ID] {
+					t.Fatalf("created id %d, want a new positive id", document.ID)
+				}
+				if strategy == sequentialIDs && document.ID != int64(i+4) {
+					t.Errorf("created id %d, want %d", document.ID, i+4)
+				}
+				// random ids don't tell how many documents there are, from
+				// the first one on
+				if strategy != sequentialIDs && i == 0 && document.ID == 4 {
+					t.Errorf("first created id %d, want a random one", document.ID)
+				}
+				if strategy == randomIDs && document.ID > 1<<31-1 {
+					t.Errorf("created id %d, want a 32-bit id", document.ID)
+				}
+				seen[document.ID] = true
+			}
+		})
+	}
+}
+
+func TestUUID(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, id := range []int64{1, 2, 1<<31 - 1, 1<<62 - 1} {
+		uuid := uuidOf(id)
+		if !format.MatchString(uuid) {
+			t.Errorf("uuidOf(%d) = %q, want a version 8 UUID", id, uuid)
+		}
+		if parsed, err := parseUUID(uuid); err != nil || parsed != id {
+			t.Errorf("parseUUID(%q) = %d, %v, want %d", uuid, parsed, err, id)
+		}
+	}
+	if uuidOf(1) == uuidOf(2) {
+		t.Error("ids 1 and 2 have the same UUID")
+	}
+	// changing a digit must not give another id
+	mistyped := []byte(uuidOf(1))
+	mistyped[0] ^= 1
+	for _, uuid := range []string{string(mistyped), "1", "", "00000000-0000-8000-8000-000000000001", "not-a-uuid-at-all-xxxxxxxxxxxxxxxxxx"} {
+		if id, err := parseUUID(uuid); err == nil {
+			t.Errorf("parseUUID(%q) = %d, want an error", uuid, id)
+		}
+	}
+}
+
+func TestUUIDMode(t *testing.T) {
+	setVar(t, idStrategyName, string(uuidIDs))
+	memory, err := newMemoryStore(false, false, uuidIDs, blobs, testDocuments(1))
+	if err != nil {
+		t.Fatalf("newMemoryStore: %v", err)
+	}
+	setVar(t, &store, Store(memory))
+	schema := newTestSchema(t)
+
+	data := mustExecute(t, schema, `mutation{create(name:"Report"){document{id}}}`, nil)
+	id, ok := lookup(data, "create", "document", "id").(string)
+	if !ok {
+		t.Fatalf("created id = %v, want a string", lookup(data, "create", "document", "id"))
+	}
+	parsed, err := parseUUID(id)
+	if err != nil {
+		t.Fatalf("created id: %v", err)
+	}
+	if parsed == 2 {
+		t.Errorf("created id %s is the sequential id 2, want a random one", id)
+	}
+	data = mustExecute(t, schema, `query($id:ID!){document(id:$id){id,name}}`, map[string]interface{}{"id": id})
+	if got := lookup(data, "document", "id"); got != id {
+		t.Errorf("document id = %v, want %v", got, id)
+	}
+	if got := lookup(data, "document", "name"); got != "Report" {
+		t.Errorf("document name = %v, want Report", got)
+	}
+	if result := execute(context.Background(), schema, `{document(id:"1"){id}}`, nil); !result.HasErrors() {
+		t.Error("document with a numeric id succeeded")
+	}
+
+	// REST paths and bodies use the UUID too
+	r := httptest.NewRequest(http.MethodGet, "/api/documents/"+id, nil)
+	r.SetPathValue("id", id)
+	w := serve(http.HandlerFunc(restGetHandler), r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET status %d: %s", w.Code, w.Body.String())
+	}
+	if got := decodeResponse(t, w)["id"]; got != id {
+		t.Errorf("REST id = %v, want %v", got, id)
+	}
+}
+
+func TestUUIDModeJSONSchema(t *testing.T) {
+	setVar(t, idStrategyName, string(uuidIDs))
+	id := documentJSONSchema()["properties"].(map[string]interface{})["id"].(map[string]interface{})
+	if id["type"] != "string" || id["format"] != "uuid" {
+		t.Errorf("JSON Schema of ids %v, want UUID strings", id)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// importedDocument is a document given as JSON to importHandler or
+// restCreateHandler
+type importedDocument struct {
+	Name        string   `json:"name"`
+	File        string   `json:"file"`
+	ContentType string   `json:"contentType"`
+	Tags        []string `json:"tags"`
+}
+
+// args returns the document as the arguments of the create mutation, as
+// mutation hooks get them
+func (d importedDocument) args() map[string]interface{} {
+	args := map[string]interface{}{"name": d.Name}
+	if d.File != "" {
+		args["file"] = d.File
+	}
+	if d.ContentType != "" {
+		args["contentType"] = d.ContentType
+	}
+	if d.Tags != nil {
+		tags := make([]interface{}, len(d.Tags))
+		for i, tag := range d.Tags {
+			tags[i] = tag
+		}
+		args["tags"] = tags
+	}
+	return args
+}
+
+// create stores the document as the create mutation does: after running the
+// mutation hooks, and publishing a create event
+func (d importedDocument) create(ctx context.Context) (Document, error) {
+	if err := runMutationHooks(ctx, "create", d.args()); err != nil {
+		return Document{}, err
+	}
+	document, err := createDocument(ctx, Document{
+		Name:        d.Name,
+		File:        d.File,
+		ContentType: d.ContentType,
+		Tags:        d.Tags,
+	}, "")
+	if err != nil {
+		return Document{}, err
+	}
+	publishEvent(changeEvent{Type: "create", Document: document, Time: time.Now()})
+	return document, nil
+}
+
+// importResult is the response of importHandler
+type importResult struct {
+	Imported int    `json:"imported"`
+	Error    string `json:"error,omitempty"`
+}
+
+// importHandler creates the documents of a JSON array, checked as create
+// checks them. The array is decoded one element at a time and each document
+// stored as soon as it is read, so large imports aren't held in memory. An
+// invalid document stops the import, leaving the documents before it
+// imported.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	if *readOnly {
+		http.Error(w, "mutations disabled", http.StatusForbidden)
+		return
+	}
+	if mutations != nil {
+		if err := mutations.acquire(r.Context()); err != nil {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer mutations.release()
+	}
+
+	result := importResult{}
+	status := http.StatusOK
+	fail := func(code int, err error) {
+		status = code
+		result.Error, _ = safeMessage(r.Context(), err)
+	}
+	decoder := json.NewDecoder(r.Body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		fail(http.StatusBadRequest, errors.New("the body must be a JSON array of documents"))
+	}
+	for status == http.StatusOK && decoder.More() {
+		var imported importedDocument
+		if err := decoder.Decode(&imported); err != nil {
+			fail(http.StatusBadRequest, fmt.Errorf("document %d: %v", result.Imported, err))
+			break
+		}
+		if _, err := imported.create(r.Context()); err != nil {
+			fail(restStatus(err), fmt.Errorf("document %d: %w", result.Imported, err))
+			break
+		}
+		result.Imported++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	memory := newTestStore(t)
+	creates := counters.snapshot().Creates
+	body := `[{"name":"Report.pdf","file":"JVBERi0xLjQK","tags":["report"]},{"name":"Notes"}]`
+	w := serve(http.HandlerFunc(importHandler), httptest.NewRequest(http.MethodPost, "/document/import", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if result := decodeResponse(t, w); result["imported"] != 2.0 {
+		t.Errorf("result = %v, want 2 imported", result)
+	}
+	if documents, _ := memory.List(context.Background()); len(documents) != 2 {
+		t.Errorf("%d documents stored, want 2", len(documents))
+	}
+	if got := counters.snapshot().Creates - creates; got != 2 {
+		t.Errorf("creates counted = %d, want 2", got)
+	}
+}
+
+func TestImportStopsAtAnInvalidDocument(t *testing.T) {
+	memory := newTestStore(t)
+	body := `[{"name":"Report"},{"file":"SGVsbG8="},{"name":"Notes"}]`
+	w := serve(http.HandlerFunc(importHandler), httptest.NewRequest(http.MethodPost, "/document/import", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+	}
+	if result := decodeResponse(t, w); result["imported"] != 1.0 || !strings.HasPrefix(result["error"].(string), "document 1:") {
+		t.Errorf("result = %v, want 1 imported and an error for document 1", result)
+	}
+	if documents, _ := memory.List(context.Background()); len(documents) != 1 {
+		t.Errorf("%d documents stored, want 1", len(documents))
+	}
+}
+
+func TestImportRunsMutationHooks(t *testing.T) {
+	memory := newTestStore(t)
+	setVar(t, &mutationHooks, nil)
+	RegisterMutationHook(func(ctx context.Context, operation string, args map[string]interface{}) error {
+		if name, _ := args["name"].(string); operation == "create" && strings.HasPrefix(name, "tmp") {
+			return errors.New("temporary documents are not allowed")
+		}
+		return nil
+	})
+	body := `[{"name":"Report"},{"name":"tmp notes"}]`
+	w := serve(http.HandlerFunc(importHandler), httptest.NewRequest(http.MethodPost, "/document/import", strings.NewReader(body)))
+	if result := decodeResponse(t, w); result["imported"] != 1.0 || !strings.Contains(result["error"].(string), "temporary documents are not allowed") {
+		t.Errorf("result = %v, want 1 imported and the error of the hook", result)
+	}
+	if documents, _ := memory.List(context.Background()); len(documents) != 1 {
+		t.Errorf("%d documents stored, want 1", len(documents))
+	}
+}
+
+// TestImportStreams checks that documents are stored as they are read, so
+// the body of a large import is never held in memory: most of the array is
+// only written once the first documents are stored.
+func TestImportStreams(t *testing.T) {
+	memory := newTestStore(t)
+	const documents = 10000
+	body, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte(`[{"name":"Document 0"}`))
+		// wait for the first document to be stored before sending the others
+		deadline := time.Now().Add(5 * time.Second)
+		for memory.describe().Documents == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if memory.describe().Documents == 0 {
+			writer.CloseWithError(errors.New("the first document wasn't stored before the end of the body"))
+			return
+		}
+		for i := 1; i < documents; i++ {
+			fmt.Fprintf(writer, `,{"name":"Document %d","file":"SGVsbG8sIFdvcmxkIQ=="}`, i)
+		}
+		writer.Write([]byte("]"))
+		writer.Close()
+	}()
+	w := serve(http.HandlerFunc(importHandler), httptest.NewRequest(http.MethodPost, "/document/import", body))
+	if result := decodeResponse(t, w); result["imported"] != float64(documents) {
+		t.Errorf("result = %v, want %d imported", result, documents)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nameIndex is an inverted index from the words of document names to the
+// documents containing them
+type nameIndex struct {
+	postings map[string]map[int64]int // word -> document id -> occurrences
+	words    map[int64][]string       // document id -> indexed words
+}
+
+func newNameIndex() *nameIndex {
+	return &nameIndex{
+		postings: map[string]map[int64]int{},
+		words:    map[int64][]string{},
+	}
+}
+
+// tokenize splits text into lowercase words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// add indexes the name of document id, replacing any previous one
+func (x *nameIndex) add(id int64, name string) {
+	x.remove(id)
+	words := tokenize(name)
+	for _, word := range words {
+		if x.postings[word] == nil {
+			x.postings[word] = map[int64]int{}
+		}
+		x.postings[word][id]++
+	}
+	x.words[id] = words
+}
+
+// remove drops document id from the index
+func (x *nameIndex) remove(id int64) {
+	for _, word := range x.words[id] {
+		delete(x.postings[word], id)
+		if len(x.postings[word]) == 0 {
+			delete(x.postings, word)
+		}
+	}
+	delete(x.words, id)
+}
+
+// search returns the ids of the documents whose name contains a word of term,
+// most relevant first: the more distinct words of term a name contains, then
+// the more often it contains them, the more relevant it is
+func (x *nameIndex) search(term string) []int64 {
+	type score struct {
+		matched     int
+		occurrences int
+	}
+	scores := map[int64]*score{}
+	seen := map[string]bool{}
+	for _, word := range tokenize(term) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		for id, n := range x.postings[word] {
+			if scores[id] == nil {
+				scores[id] = &score{}
+			}
+			scores[id].matched++
+			scores[id].occurrences += n
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := scores[ids[i]], scores[ids[j]]
+		if a.matched != b.matched {
+			return a.matched > b.matched
+		}
+		if a.occurrences != b.occurrences {
+			return a.occurrences > b.occurrences
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "Annual report"},
+		Document{ID: 2, Name: "Report of the annual report"},
+		Document{ID: 3, Name: "Meeting notes"},
+	)
+	schema := newTestSchema(t)
+	search := func(term string) string {
+		return idsOf(mustExecute(t, schema, `query($term:String!){search(term:$term){id}}`, map[string]interface{}{"term": term})["search"])
+	}
+	for _, test := range []struct {
+		term string
+		want string
+	}{
+		// more matched words first, then more occurrences
+		{"annual REPORT", "[2 1]"},
+		{"notes", "[3]"},
+		{"budget", "[]"},
+	} {
+		if got := search(test.term); got != test.want {
+			t.Errorf("search(%q) = %s, want %s", test.term, got, test.want)
+		}
+	}
+
+	// the index follows renames and deletes
+	mustExecute(t, schema, `mutation{update(id:3,name:"Budget"){document{id}}}`, nil)
+	mustExecute(t, schema, `mutation{delete(id:1){deleted}}`, nil)
+	if got := search("notes"); got != "[]" {
+		t.Errorf("search(notes) after the rename = %s, want []", got)
+	}
+	if got := search("budget"); got != "[3]" {
+		t.Errorf("search(budget) after the rename = %s, want [3]", got)
+	}
+	if got := search("annual"); got != "[2]" {
+		t.Errorf("search(annual) after the delete = %s, want [2]", got)
+	}
+}
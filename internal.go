@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// internalError is an unexpected failure, e.g. of the store, as opposed to
+// an error caused by the request. With -error-detail=safe, clients only get
+// a reference to it, which is logged with the error.
+type internalError struct {
+	err error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+// markInternal makes err an internal error unless it is a failure the
+// client can act on, like a missing document
+func markInternal(err error) error {
+	if storeFailure(err) {
+		return &internalError{err: err}
+	}
+	return err
+}
+
+// internalErrorStore marks the failures of its store as internal errors
+type internalErrorStore struct {
+	Store
+}
+
+func (s internalErrorStore) List(ctx context.Context) ([]Document, error) {
+	documents, err := s.Store.List(ctx)
+	return documents, markInternal(err)
+}
+
+func (s internalErrorStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	documents, err := s.Store.ListSorted(ctx, field)
+	return documents, markInternal(err)
+}
+
+func (s internalErrorStore) Get(ctx context.Context, id int64) (Document, error) {
+	document, err := s.Store.Get(ctx, id)
+	return document, markInternal(err)
+}
+
+func (s internalErrorStore) Search(ctx context.Context, term string) ([]Document, error) {
+	documents, err := s.Store.Search(ctx, term)
+	return documents, markInternal(err)
+}
+
+func (s internalErrorStore) NextID(ctx context.Context) (int64, error) {
+	id, err := s.Store.NextID(ctx)
+	return id, markInternal(err)
+}
+
+func (s internalErrorStore) Create(ctx context.Context, document Document) (Document, error) {
+	created, err := s.Store.Create(ctx, document)
+	return created, markInternal(err)
+}
+
+func (s internalErrorStore) Update(ctx context.Context, document Document) (Document, error) {
+	updated, err := s.Store.Update(ctx, document)
+	return updated, markInternal(err)
+}
+
+func (s internalErrorStore) Delete(ctx context.Context, id int64) (Document, error) {
+	deleted, err := s.Store.Delete(ctx, id)
+	return deleted, markInternal(err)
+}
+
+// safeMessage returns the message of err for clients. Internal errors are
+// logged under a new reference, and replaced by it if -error-detail is safe.
+func safeMessage(ctx context.Context, err error) (string, bool) {
+	var internal *internalError
+	if *errorDetail != "safe" || !errors.As(err, &internal) {
+		return err.Error(), false
+	}
+	reference := newRequestID()
+	log.Printf("request %s: internal error %s: %v", requestIDFrom(ctx), reference, err)
+	return "internal error, reference " + reference, true
+}
+
+// maskErrors replaces the messages of the internal errors among errs as
+// safeMessage does
+func maskErrors(ctx context.Context, errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	if *errorDetail != "safe" {
+		return errs
+	}
+	masked := make([]gqlerrors.FormattedError, len(errs))
+	for i, formatted := range errs {
+		masked[i] = formatted
+		err := resolverError(formatted)
+		if err == nil {
+			continue
+		}
+		if message, ok := safeMessage(ctx, err); ok {
+			masked[i].Message = message
+			masked[i].Extensions = map[string]interface{}{"code": "internal"}
+		}
+	}
+	return masked
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestErrorDetailSafe(t *testing.T) {
+	var output bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	broken := &brokenStore{Store: newTestStore(t, testDocuments(1)...)}
+	setVar(t, &store, Store(internalErrorStore{broken}))
+	schema := newTestSchema(t)
+
+	// known errors stay descriptive
+	setVar(t, errorDetail, "safe")
+	result := execute(context.Background(), schema, `mutation{update(id:99,name:"New"){document{id}}}`, nil)
+	if !result.HasErrors() || result.Errors[0].Message != "document 99 not found" {
+		t.Errorf("missing document: errors %v, want document 99 not found", result.Errors)
+	}
+
+	broken.broken = true
+	result = execute(context.Background(), schema, `{document(id:1){name}}`, nil)
+	if !result.HasErrors() {
+		t.Fatal("the query succeeded with a broken store")
+	}
+	message := result.Errors[0].Message
+	reference, ok := strings.CutPrefix(message, "internal error, reference ")
+	if !ok || strings.Contains(message, errBroken.Error()) || result.Errors[0].Extensions["code"] != "internal" {
+		t.Errorf("error %q with extensions %v, want it masked", message, result.Errors[0].Extensions)
+	}
+	if logged := output.String(); !strings.Contains(logged, reference) || !strings.Contains(logged, errBroken.Error()) {
+		t.Errorf("logged %q, want the error under reference %s", logged, reference)
+	}
+
+	setVar(t, errorDetail, "full")
+	result = execute(context.Background(), schema, `{document(id:1){name}}`, nil)
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, errBroken.Error()) {
+		t.Errorf("full detail: errors %v, want the store error", result.Errors)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// errBusy is returned when too many mutations are waiting to run
+var errBusy = errors.New("too many concurrent mutations, try again later")
+
+// mutationLimiter bounds the number of mutations running at once. Mutations
+// beyond it wait for a slot, up to a maximum number of waiting mutations.
+type mutationLimiter struct {
+	slots     chan struct{}
+	waiting   atomic.Int64
+	maxQueued int64
+}
+
+// mutations limits concurrent mutations, if -max-concurrent-mutations is set
+var mutations *mutationLimiter
+
+func newMutationLimiter(size, maxQueued int) *mutationLimiter {
+	return &mutationLimiter{slots: make(chan struct{}, size), maxQueued: int64(maxQueued)}
+}
+
+// acquire waits for a slot to run a mutation, and must be followed by
+// release. It returns errBusy right away if the queue is full, or the error
+// of ctx if it is done while waiting.
+func (l *mutationLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if l.waiting.Add(1) > l.maxQueued {
+		l.waiting.Add(-1)
+		return errBusy
+	}
+	defer l.waiting.Add(-1)
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken by acquire
+func (l *mutationLimiter) release() {
+	<-l.slots
+}
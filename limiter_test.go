@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyStore records the highest number of creates running at once
+type concurrencyStore struct {
+	Store
+	running, max atomic.Int64
+}
+
+func (s *concurrencyStore) Create(ctx context.Context, document Document) (Document, error) {
+	running := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		max := s.max.Load()
+		if running <= max || s.max.CompareAndSwap(max, running) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return s.Store.Create(ctx, document)
+}
+
+func TestMutationLimiter(t *testing.T) {
+	newTestStore(t)
+	counting := &concurrencyStore{Store: store}
+	setVar(t, &store, Store(counting))
+	setVar(t, &mutations, newMutationLimiter(2, 100))
+	handler := documentHandler(newTestSchema(t))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if w := postQuery(handler, fmt.Sprintf(`mutation{create(name:"Document %d"){document{id}}}`, i)); w.Code != http.StatusOK {
+				t.Errorf("create %d: status %d: %s", i, w.Code, w.Body)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := counting.max.Load(); got > 2 {
+		t.Errorf("%d mutations ran at once, want at most 2", got)
+	}
+}
+
+func TestMutationLimiterQueueFull(t *testing.T) {
+	newTestStore(t)
+	setVar(t, &mutations, newMutationLimiter(1, 0))
+	handler := documentHandler(newTestSchema(t))
+	if err := mutations.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer mutations.release()
+	w := postQuery(handler, `mutation{create(name:"New"){document{id}}}`)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("status %d, Retry-After %q, want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	// queries don't wait for mutations
+	if w := postQuery(handler, `{list{id}}`); w.Code != http.StatusOK {
+		t.Errorf("query: status %d, want 200", w.Code)
+	}
+}
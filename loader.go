@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// loadResult is the outcome of loading one document
+type loadResult struct {
+	document Document
+	err      error
+}
+
+// documentLoader batches the documents resolvers ask for by id during one
+// query. Resolvers queue their id with load and get a thunk; the first
+// thunk run fetches every queued id at once, each id only once.
+type documentLoader struct {
+	mu      sync.Mutex
+	pending []int64
+	results map[int64]loadResult
+	// batch fetches the documents with the given ids
+	batch func(ctx context.Context, ids []int64) map[int64]loadResult
+}
+
+type documentLoaderKey struct{}
+
+func newDocumentLoader() *documentLoader {
+	return &documentLoader{results: map[int64]loadResult{}, batch: getDocuments}
+}
+
+// withDocumentLoader returns a context whose resolvers share a document
+// loader
+func withDocumentLoader(ctx context.Context) context.Context {
+	return context.WithValue(ctx, documentLoaderKey{}, newDocumentLoader())
+}
+
+// documentLoaderFrom returns the document loader of ctx, or a new one if it
+// has none
+func documentLoaderFrom(ctx context.Context) *documentLoader {
+	if loader, ok := ctx.Value(documentLoaderKey{}).(*documentLoader); ok {
+		return loader
+	}
+	return newDocumentLoader()
+}
+
+// load queues id and returns a thunk returning its document once the batch
+// has run
+func (l *documentLoader) load(ctx context.Context, id int64) func() (Document, error) {
+	l.mu.Lock()
+	if _, ok := l.results[id]; !ok {
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+	return func() (Document, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			for id, result := range l.batch(ctx, l.pending) {
+				l.results[id] = result
+			}
+			l.pending = nil
+		}
+		result := l.results[id]
+		return result.document, result.err
+	}
+}
+
+// getDocuments is the batch function of document loaders, getting each
+// distinct id from the store once
+func getDocuments(ctx context.Context, ids []int64) map[int64]loadResult {
+	results := map[int64]loadResult{}
+	for _, id := range ids {
+		if _, ok := results[id]; ok {
+			continue
+		}
+		document, err := store.Get(ctx, id)
+		results[id] = loadResult{document: document, err: err}
+	}
+	return results
+}
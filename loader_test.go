@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// countingStore counts the gets of each id
+type countingStore struct {
+	Store
+	mu   sync.Mutex
+	gets map[int64]int
+}
+
+func (s *countingStore) Get(ctx context.Context, id int64) (Document, error) {
+	s.mu.Lock()
+	s.gets[id]++
+	s.mu.Unlock()
+	return s.Store.Get(ctx, id)
+}
+
+func TestDocumentLoaderBatches(t *testing.T) {
+	newTestStore(t, testDocuments(2)...)
+	var batches [][]int64
+	loader := newDocumentLoader()
+	loader.batch = func(ctx context.Context, ids []int64) map[int64]loadResult {
+		batches = append(batches, ids)
+		return getDocuments(ctx, ids)
+	}
+	ctx := context.Background()
+	thunks := []func() (Document, error){loader.load(ctx, 1), loader.load(ctx, 2), loader.load(ctx, 1)}
+	for i, thunk := range thunks {
+		document, err := thunk()
+		if want := []int64{1, 2, 1}[i]; err != nil || document.ID != want {
+			t.Errorf("thunk %d = document %d, %v, want %d", i, document.ID, err, want)
+		}
+	}
+	if len(batches) != 1 {
+		t.Errorf("batches %v, want one", batches)
+	}
+	// loaded ids are not fetched again
+	loader.load(ctx, 2)()
+	if len(batches) != 1 {
+		t.Errorf("batches %v after loading 2 again", batches)
+	}
+}
+
+func TestDocumentQueriesShareGets(t *testing.T) {
+	counting := &countingStore{Store: newTestStore(t, testDocuments(2)...), gets: map[int64]int{}}
+	setVar(t, &store, Store(counting))
+	data := mustExecute(t, newTestSchema(t), "{a:document(id:1){name} b:document(id:1){id} c:document(id:2){id}}", nil)
+	if lookup(data, "a", "name") != "Document 1" || lookup(data, "b", "id") != 1.0 || lookup(data, "c", "id") != 2.0 {
+		t.Errorf("data %v", data)
+	}
+	if counting.gets[1] != 1 || counting.gets[2] != 1 {
+		t.Errorf("gets %v, want one per id", counting.gets)
+	}
+}
@@ -1,214 +1,466 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"math/rand"
+	"log"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
+
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"golang.org/x/text/language"
 )
 
-// Document contains infomation about one document
+// autoName makes the name argument of create optional; documents created
+// without a name get a generated "Untitled <n>" name instead.
+var autoName = flag.Bool("auto-name", false, "make name optional on create and generate \"Untitled <n>\" when absent")
 
-type Document struct {
-	ID    int64   `json:"id"`
-	Name  string  `json:"name,omitempty"`
-	File  string  `json:"file,omitempty"`
-}
+// compress gzips file contents kept in the in-memory store
+var compress = flag.Bool("compress", false, "gzip file contents in the in-memory store")
 
-var documents = []Document{
-	{
-		ID:    1,
-		Name:  "Document one",
-		File:  "a23hkjhl03209n2lh34sd009f92h3h4120098fwejk13h342h...",
-	},
-	{
-		ID:    2,
-		Name:  "Document 2",
-		File:  "a23hkjhl03209n2lh34sd009f92h3h4120098fwejk13h342h...",
-	},
-	{
-		ID:    3,
-		Name:  "Document 3",
-		File:  "a23hkjhl03209n2lh34sd009f92h3h4120098fwejk13h342h...",
-	},
-}
+// allowedExtensions restricts the file types documents can have
+var allowedExtensions = flag.String("allowed-extensions", "", "comma-separated list of allowed file extensions, e.g. \".pdf,.png\"; empty allows all")
+
+// relay exposes the schema the way Relay clients expect: documents are
+// identified by global ids and implement the Node interface
+var relay = flag.Bool("relay", false, "identify documents by Relay global ids and add the node and nodes queries")
+
+// readOnly serves queries only, e.g. on a read replica
+var readOnly = flag.Bool("read-only", false, "serve queries only; mutations are rejected")
+
+// prettyDefault indents JSON responses unless the request sets ?pretty=false
+var prettyDefault = flag.Bool("pretty", false, "indent JSON responses by default; requests can override it with ?pretty=true|false")
+
+// maxPageSize caps the number of documents a list query returns
+var maxPageSize = flag.Int("max-page-size", 100, "maximum number of documents returned by a list query")
 
-var documentType = graphql.NewObject(
-	graphql.ObjectConfig{
-		Name: "Document",
-		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type: graphql.Int,
-			},
-			"name": &graphql.Field{
-				Type: graphql.String,
-			},
-			"file": &graphql.Field{
-				Type: graphql.String,
-			},
-		},
-	},
+// strictPageSize rejects list queries asking for more than maxPageSize
+// documents instead of capping them
+var strictPageSize = flag.Bool("strict-page-size", false, "reject list limits above -max-page-size instead of capping them")
+
+// Timeouts of the HTTP server, so slow or hung clients can't hold
+// connections forever
+var (
+	readTimeout  = flag.Duration("read-timeout", 10*time.Second, "maximum duration for reading a request")
+	writeTimeout = flag.Duration("write-timeout", 30*time.Second, "maximum duration for writing a response")
+	idleTimeout  = flag.Duration("idle-timeout", 120*time.Second, "maximum duration a keep-alive connection is kept idle")
 )
 
-var queryType = graphql.NewObject(
-	graphql.ObjectConfig{
-		Name: "Query",
-		Fields: graphql.Fields{
-			/* Get (read) single document by id
-			   http://localhost:8080/document?query={document(id:1){name,file}}
-			*/
-			"document": &graphql.Field{
-				Type:        documentType,
-				Description: "Get document by id",
-				Args: graphql.FieldConfigArgument{
-					"id": &graphql.ArgumentConfig{
-						Type: graphql.Int,
-					},
-				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					id, ok := p.Args["id"].(int)
-					if ok {
-						// Find document
-						for _, document := range documents {
-							if int(document.ID) == id {
-								return document, nil
-							}
-						}
-					}
-					return nil, nil
-				},
-			},
-			/* Get (read) documents list
-			   http://localhost:8080/document?query={list{id,name,file}}
-			*/
-			"list": &graphql.Field{
-				Type:        graphql.NewList(documentType),
-				Description: "Get document list",
-				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-					return documents, nil
-				},
-			},
-		},
-	}
+// defaultSort is the order of lists whose query doesn't give one
+var defaultSort = flag.String("default-sort", "id:asc", "order of list when the query gives no sortBy or descending, as field:asc or field:desc, e.g. name:desc")
+
+// collationLocale is the locale whose rules order names when sorting by name
+var collationLocale = flag.String("collation-locale", "en", "locale used to sort document names, e.g. \"fr\" or \"sv\"")
+
+// Webhooks notified of document changes
+var (
+	webhookURLs     = flag.String("webhook-urls", "", "comma-separated list of URLs receiving a POST for every document change")
+	webhookTimeout  = flag.Duration("webhook-timeout", 5*time.Second, "timeout of a webhook delivery attempt")
+	webhookAttempts = flag.Int("webhook-attempts", 3, "maximum number of delivery attempts per webhook event")
+	webhookCoalesce = flag.Duration("webhook-coalesce", 0, "when set, post the events happening within this long of each other together, as one documentsChanged event; 0 posts every event on its own")
 )
 
-var mutationType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Mutation",
-	Fields: graphql.Fields{
-		/* Create new document item
-		http://localhost:8080/document?query=mutation+_{create(name:"Test File",file:"test.pdf"){id,name,file}}
-		*/
-		"create": &graphql.Field{
-			Type:        documentType,
-			Description: "Create new document",
-			Args: graphql.FieldConfigArgument{
-				"name": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.String),
-				},
-				"file": &graphql.ArgumentConfig{
-					Type: graphql.String,
-				},
-			},
-			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				rand.Seed(time.Now().UnixNano())
-				document := Document{
-					ID:    int64(rand.Intn(100000)), // generate random ID
-					Name:  params.Args["name"].(string),
-					File:  params.Args["file"].(string),
-				}
-				documents = append(documents, document)
-				return document, nil
-			},
-		},
-		/* Update document by id
-		   http://localhost:8080/document?query=mutation+_{update(id:1,name:"test name"file:"test2.pdf"){id,name,file}}
-		*/
-		"update": &graphql.Field{
-			Type:        documentType,
-			Description: "Update document by id",
-			Args: graphql.FieldConfigArgument{
-				"id": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.Int),
-				},
-				"name": &graphql.ArgumentConfig{
-					Type: graphql.String,
-				},
-				"file": &graphql.ArgumentConfig{
-					Type: graphql.String,
-				},
-			},
-			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				id, _ := params.Args["id"].(int)
-				name, nameOk := params.Args["name"].(string)
-				file, fileOk := params.Args["file"].(string)
-				document := Document{}
-				for i, p := range documents {
-					if int64(id) == p.ID {
-						if nameOk {
-							documents[i].Name = name
-						}
-						if fileOk {
-							documents[i].File = file
-						}
-						document = documents[i]
-						break
-					}
-				}
-				return document, nil
-			},
-		},
-		/* Delete document by id
-		   http://localhost:8080/document?query=mutation+_{delete(id:1){id,name,file}}
-		*/
-		"delete": &graphql.Field{
-			Type:        documentType,
-			Description: "Delete document by id",
-			Args: graphql.FieldConfigArgument{
-				"id": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.Int),
-				},
-			},
-			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-				id, _ := params.Args["id"].(int)
-				document := Document{}
-				for i, p := range documents {
-					if int64(id) == p.ID {
-						document = documents[i]
-						// Remove from document list
-						documents = append(documents[:i], documents[i+1:]...)
-					}
-				}
-				return document, nil
-			},
-		},
-	},
-})
-
-var schema, _ = graphql.NewSchema(
-	graphql.SchemaConfig{
-		Query:    queryType,
-		Mutation: mutationType,
-	},
+// snakeCaseFields names the fields of object types in snake_case
+var snakeCaseFields = flag.Bool("snake-case", false, "name object fields in snake_case, e.g. content_type instead of contentType")
+
+// cacheSize enables caching query results
+var cacheSize = flag.Int("cache-size", 0, "number of query results to cache; 0 disables caching")
+
+// queryTimeout bounds the time spent resolving a request
+var queryTimeout = flag.Duration("query-timeout", 0, "maximum duration of a request; lists return partial results when it runs out. 0 disables it")
+
+// blobDir keeps files in a directory instead of in memory
+var blobDir = flag.String("blob-dir", "", "directory keeping the files of documents; by default they are kept in memory")
+
+// idStrategyName selects how created documents get their id
+var idStrategyName = flag.String("id-strategy", "sequential", "how created documents get their id: sequential, random, or uuid for random ids shown as UUID strings")
+
+// userCostBudget and userCostWindow bound the total cost of the queries of
+// each authenticated user
+var (
+	userCostBudget = flag.Int("user-cost-budget", 0, "maximum total cost of the queries of an authenticated user over -user-cost-window; 0 disables the limit")
+	userCostWindow = flag.Duration("user-cost-window", time.Minute, "rolling window over which -user-cost-budget applies")
 )
 
-func executeQuery(query string, schema graphql.Schema) *graphql.Result {
-	result := graphql.Do(graphql.Params{
-		Schema:        schema,
-		RequestString: query,
-	})
+// corsOrigins are the origins browsers may call the API from
+var corsOrigins = flag.String("cors-origins", "", "comma-separated list of origins allowed to call the API from browsers, with credentials; * allows any other origin without credentials")
+
+// errorDetail is how much clients are told about internal errors
+var errorDetail = flag.String("error-detail", "full", "detail of internal errors given to clients: full, or safe to only give a reference to the logged error")
+
+// responseFormat is the shape of responses: the GraphQL spec's, or the
+// flatter rest one
+var responseFormat = flag.String("response-format", "graphql", "shape of responses: graphql ({data, errors}) or rest (the value of the only field of single-field queries)")
+
+// maxQueryLength rejects long queries before they reach the parser
+var maxQueryLength = flag.Int("max-query-length", 64*1024, "maximum length in bytes of a query string; 0 disables the limit")
+
+// seedIDBase is the id of the first seed document, so demo data can be moved
+// out of the way of imported ids
+var seedIDBase = flag.Int64("seed-id-base", 1, "id of the first seed document; the following ones are numbered from it")
+
+// demoSeed replaces the seed documents with generated ones
+var demoSeed = flag.Int64("demo-seed", 0, "start with a set of demo documents generated from this seed instead of the three seed documents; the same seed gives the same documents. 0 keeps the seed documents")
+
+// randomSeed seeds the random numbers of the server, e.g. random ids
+var randomSeed = flag.Int64("random-seed", 0, "seed of the random ids, randomDocument and retry jitter, so that a run can be reproduced; 0 seeds them from the time")
+
+// uniqueNames rejects documents named like another one
+var uniqueNames = flag.Bool("unique-names", false, "reject creating or renaming a document to the name of another one, ignoring case")
+
+// authTokens turns authentication on
+var authTokens = flag.String("auth-tokens", "", "comma-separated list of token=user pairs accepted as bearer tokens, with the scopes of the token after the user, each after a +; anonymous clients can then only run introspection queries. Empty disables authentication")
+
+// fileScope is the scope tokens need to read the files of documents
+var fileScope = flag.String("file-scope", "", "scope tokens need to read the files of documents, e.g. read:file; files read as null without it. Empty lets every client read files")
+
+// Retries of store operations failing with a transient error, e.g. writing
+// a blob. Files are read from the blob store outside of store operations,
+// when a query selects them, and these reads aren't retried.
+var (
+	storeAttempts = flag.Int("store-attempts", 3, "maximum number of attempts of a store operation failing with a transient error")
+	storeBackoff  = flag.Duration("store-backoff", 50*time.Millisecond, "wait before retrying a store operation, doubled after each attempt")
+)
+
+// trimWhitespace trims the names and tags given to create and update
+var trimWhitespace = flag.Bool("trim-whitespace", false, "remove leading and trailing whitespace from the names and tags given to create and update")
+
+// maxTags caps the number of tags of a document
+var maxTags = flag.Int("max-tags", 20, "maximum number of tags of a document; 0 disables the limit")
+
+// Logging of the queries run, keeping file contents and secrets out of it
+var (
+	logQueries   = flag.Bool("log-queries", false, "log the query and variables of every request")
+	redactKeys   = flag.String("redact-keys", "file,content,patch,password,token", "comma-separated list of variables, and fields of input variables, whose values are redacted in the query log")
+	redactLength = flag.Int("redact-length", 64, "redact strings longer than this in the query log; 0 disables it")
+)
+
+// defaultContentType is the content type of files whose type can't be
+// detected
+var defaultContentType = flag.String("default-content-type", "application/octet-stream", "content type of files whose type can't be detected, when none is given")
+
+// debugStore serves /debug/store
+var debugStore = flag.Bool("debug-store", false, "serve the type and size of the store as JSON at /debug/store")
+
+// Backpressure on writes, so bursts of mutations queue instead of all
+// hitting the store at once
+var (
+	maxConcurrentMutations = flag.Int("max-concurrent-mutations", 0, "maximum number of mutations running at once; 0 disables the limit")
+	mutationQueue          = flag.Int("mutation-queue", 100, "maximum number of mutations waiting to run when -max-concurrent-mutations is reached; more are rejected with a 503")
+)
+
+// pollTimeout and changeLogSize configure the long-polling of
+// /document/changes
+var (
+	pollTimeout   = flag.Duration("poll-timeout", 25*time.Second, "maximum time /document/changes waits for a change before responding with none; keep it under -write-timeout")
+	changeLogSize = flag.Int("change-log-size", 1000, "number of change events kept for /document/changes")
+)
+
+// degradeRetry serves reads from a snapshot when the store fails, and
+// rejects mutations until the store is probed again
+var degradeRetry = flag.Duration("degrade-retry", 0, "when set, serve reads from the last known documents if the store fails, and reject mutations for this long after a failed write before trying again. 0 disables degraded mode")
+
+// untitledCount numbers the names generated in auto-name mode
+var untitledCount atomic.Int64
+
+// store holds the documents served by the API
+var store Store
+
+// queries caches query results, if enabled
+var queries *queryCache
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newServer returns the HTTP server listening on addr, with the configured
+// timeouts
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  *readTimeout,
+		WriteTimeout: *writeTimeout,
+		IdleTimeout:  *idleTimeout,
+	}
+}
+
+// redactURL masks the password of a URL, if it has one
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// don't risk logging a secret we can't locate
+		return "<invalid URL>"
+	}
+	return u.Redacted()
+}
+
+// logConfig logs the configuration the server runs with
+func logConfig(addr string) {
+	webhooks := []string{}
+	for _, webhookURL := range splitList(*webhookURLs) {
+		webhooks = append(webhooks, redactURL(webhookURL))
+	}
+	storeType := "memory"
+	if *blobDir != "" {
+		storeType = "memory with blobs in " + *blobDir
+	}
+	slog.Info("server starting",
+		"addr", addr,
+		"store", storeType,
+		"compress", *compress,
+		"uniqueNames", *uniqueNames,
+		"idStrategy", *idStrategyName,
+		"seedIDBase", *seedIDBase,
+		"demoSeed", *demoSeed,
+		"randomSeed", *randomSeed,
+		"readOnly", *readOnly,
+		"relay", *relay,
+		"autoName", *autoName,
+		"allowedExtensions", *allowedExtensions,
+		"maxQueryLength", *maxQueryLength,
+		"maxTags", *maxTags,
+		"trimWhitespace", *trimWhitespace,
+		"maxPageSize", *maxPageSize,
+		"strictPageSize", *strictPageSize,
+		"queryTimeout", *queryTimeout,
+		"readTimeout", *readTimeout,
+		"writeTimeout", *writeTimeout,
+		"idleTimeout", *idleTimeout,
+		"storeAttempts", *storeAttempts,
+		"storeBackoff", *storeBackoff,
+		"degradeRetry", *degradeRetry,
+		"pollTimeout", *pollTimeout,
+		"changeLogSize", *changeLogSize,
+		"logQueries", *logQueries,
+		"maxConcurrentMutations", *maxConcurrentMutations,
+		"mutationQueue", *mutationQueue,
+		"cacheSize", *cacheSize,
+		"collationLocale", *collationLocale,
+		"defaultSort", *defaultSort,
+		"snakeCase", *snakeCaseFields,
+		"responseFormat", *responseFormat,
+		"errorDetail", *errorDetail,
+		"corsOrigins", *corsOrigins,
+		"fileScope", *fileScope,
+		"userCostBudget", *userCostBudget,
+		"userCostWindow", *userCostWindow,
+		"webhooks", webhooks,
+		"webhookCoalesce", *webhookCoalesce,
+		"authentication", len(tokens) > 0,
+	)
+}
+
+// executeQuery runs the request, serving queries from the cache when
+// caching is enabled
+func executeQuery(ctx context.Context, req graphqlRequest, schema graphql.Schema) *graphql.Result {
+	req.parse()
+	if queries == nil || operationType(req.document, req.OperationName) != ast.OperationTypeQuery {
+		return runQuery(ctx, req, schema)
+	}
+	if result, ok := queries.get(ctx, req); ok {
+		return result
+	}
+	generation := queries.generation.Load()
+	ctx, reads := withReadTracker(ctx)
+	result := runQuery(ctx, req, schema)
+	if _, partial := result.Extensions["partial"]; !result.HasErrors() && !partial && !reads.uncacheable {
+		queries.put(req, result, reads, generation)
+	}
+	return result
+}
+
+func runQuery(ctx context.Context, req graphqlRequest, schema graphql.Schema) *graphql.Result {
+	req.parse()
+	if *queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *queryTimeout)
+		defer cancel()
+	}
+	ctx, partial := withPartialFlag(ctx)
+	ctx = withDocumentLoader(ctx)
+	ctx = withRequestVariables(ctx, req.Variables)
+	result := executeParsed(ctx, req, schema)
 	if len(result.Errors) > 0 {
-		fmt.Printf("errors: %v", result.Errors)
+		fmt.Printf("request %s errors: %v\n", requestIDFrom(ctx), result.Errors)
+	}
+	result.Errors = maskErrors(ctx, result.Errors)
+	result.Errors = addErrorCodes(result.Errors)
+	if cost, ok := queryCost(schema, req.document, req.OperationName); ok {
+		setExtension(result, "cost", cost)
+	}
+	if partial.Load() {
+		setExtension(result, "partial", true)
 	}
 	return result
 }
 
-func main() {
-	http.HandleFunc("/document", func(w http.ResponseWriter, r *http.Request) {
-		result := executeQuery(r.URL.Query().Get("query"), schema)
-		json.NewEncoder(w).Encode(result)
+// executeParsed runs req like graphql.Do, from its parsed query
+func executeParsed(ctx context.Context, req graphqlRequest, schema graphql.Schema) *graphql.Result {
+	if req.parseErr != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(req.parseErr)}
+	}
+	if !req.validated {
+		if validation := graphql.ValidateDocument(&schema, req.document, nil); !validation.IsValid {
+			return &graphql.Result{Errors: validation.Errors}
+		}
+	}
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           req.document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
 	})
-	fmt.Println("Server is running on port 8080")
-	http.ListenAndServe(":8080", nil)
-}
\ No newline at end of file
+}
+
+// setExtension sets an entry of the extensions of result
+func setExtension(result *graphql.Result, key string, value interface{}) {
+	if result.Extensions == nil {
+		result.Extensions = map[string]interface{}{}
+	}
+	result.Extensions[key] = value
+}
+
+func main() {
+	flag.Parse()
+	if _, err := language.Parse(*collationLocale); err != nil {
+		log.Fatalf("invalid -collation-locale: %v", err)
+	}
+	order, err := parseSortOrder(*defaultSort)
+	if err != nil {
+		log.Fatalf("invalid -default-sort: %v", err)
+	}
+	defaultSortOrder = order
+	if *responseFormat != "graphql" && *responseFormat != "rest" {
+		log.Fatalf("invalid -response-format %q, expected graphql or rest", *responseFormat)
+	}
+	if *errorDetail != "full" && *errorDetail != "safe" {
+		log.Fatalf("invalid -error-detail %q, expected full or safe", *errorDetail)
+	}
+	if *randomSeed != 0 {
+		seedRandom(*randomSeed)
+	}
+	seeds := seedDocumentsFrom(*seedIDBase)
+	if *demoSeed != 0 {
+		seeds = demoDocuments(*demoSeed, *seedIDBase)
+	}
+	// ids are GraphQL Ints, which are 32-bit
+	if *seedIDBase < 1 || *seedIDBase > math.MaxInt32-int64(len(seeds)) {
+		log.Fatalf("invalid -seed-id-base %d", *seedIDBase)
+	}
+	ids, err := parseIDStrategy(*idStrategyName)
+	if err != nil {
+		log.Fatalf("invalid -id-strategy: %v", err)
+	}
+	if ids == uuidIDs && *relay {
+		// Relay clients get global ids instead
+		log.Fatal("-id-strategy uuid cannot be used with -relay")
+	}
+	if tokens, err = parseTokens(*authTokens); err != nil {
+		log.Fatalf("invalid -auth-tokens: %v", err)
+	}
+	if *changeLogSize < 1 {
+		log.Fatalf("invalid -change-log-size %d", *changeLogSize)
+	}
+
+	// the server answers health checks while the store is set up, and
+	// other requests with a 503 until it is ready
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/", whenReady(http.DefaultServeMux))
+	server := newServer(":8080", withCORS(withRequestID(withUser(mux))))
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	logConfig(server.Addr)
+	go func() {
+		setUpStore(seeds, ids)
+		registerRoutes()
+		ready.Store(true)
+	}()
+	log.Fatal(server.Serve(listener))
+}
+
+// setUpStore creates the store with the seed documents, wrapped as
+// configured by the flags
+func setUpStore(seeds []Document, ids idStrategy) {
+	if *blobDir != "" {
+		fsBlobs, err := newFSBlobStore(*blobDir)
+		if err != nil {
+			log.Fatalf("failed to open blob store: %v", err)
+		}
+		blobs = fsBlobs
+	}
+	memory, err := newMemoryStore(*compress, *uniqueNames, ids, blobs, seeds)
+	if err != nil {
+		log.Fatalf("failed to create store: %v", err)
+	}
+	store = internalErrorStore{memory}
+	if *storeAttempts > 1 {
+		store = retryStore{Store: store, attempts: *storeAttempts, backoff: *storeBackoff}
+	}
+	if *degradeRetry > 0 {
+		degrading := newDegradingStore(store, *degradeRetry)
+		// take the first snapshot
+		if _, err := degrading.List(context.Background()); err != nil {
+			log.Fatalf("failed to list documents: %v", err)
+		}
+		store = degrading
+	}
+	store = newSharedReadStore(store)
+	changes = newChangeLog(*changeLogSize)
+	if *userCostBudget > 0 {
+		budgets = newCostBudget(*userCostBudget, *userCostWindow)
+	}
+	if *maxConcurrentMutations > 0 {
+		mutations = newMutationLimiter(*maxConcurrentMutations, *mutationQueue)
+	}
+	if *cacheSize > 0 {
+		queries = newQueryCache(*cacheSize)
+		store = trackingStore{store}
+	}
+}
+
+// registerRoutes registers the handlers of the API on the default mux
+func registerRoutes() {
+	var latest graphql.Schema
+	for _, version := range schemaVersions {
+		schema, err := newSchema(version)
+		if err != nil {
+			log.Fatalf("failed to create %s schema: %v", version.name, err)
+		}
+		http.HandleFunc("/"+version.name+"/document", documentHandler(schema))
+		latest = schema
+	}
+
+	http.HandleFunc("/document", documentHandler(latest))
+	http.HandleFunc("GET /document/{id}/file", requireUser(fileHandler))
+	http.HandleFunc("GET /document/export.csv", requireUser(exportHandler))
+	http.HandleFunc("GET /document/changes", requireUser(changesHandler))
+	http.HandleFunc("POST /document/import", requireUser(importHandler))
+	http.HandleFunc("POST /api/documents", requireUser(restCreateHandler))
+	http.HandleFunc("GET /api/documents/{id}", requireUser(restGetHandler))
+	if *debugStore {
+		http.HandleFunc("GET /debug/store", requireUser(debugStoreHandler))
+	}
+	http.HandleFunc("GET /schema.json", jsonSchemaHandler)
+}
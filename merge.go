@@ -0,0 +1,24 @@
+package main
+
+// mergeDocuments returns into with the content of from merged in: tags are
+// unioned, and fields only from has are taken from it. Fields both have are
+// kept from into, or from from if keepFrom is set. The file and its content
+// type go together.
+func mergeDocuments(into, from Document, keepFrom bool) (Document, error) {
+	into, err := withFile(into)
+	if err != nil {
+		return into, err
+	}
+	if from, err = withFile(from); err != nil {
+		return into, err
+	}
+	if from.Name != "" && (into.Name == "" || keepFrom) {
+		into.Name = from.Name
+	}
+	if from.File != "" && (into.File == "" || keepFrom) {
+		into.setFile(from.File)
+		into.ContentType = from.ContentType
+	}
+	into.Tags = uniqueTags(append(into.Tags, from.Tags...))
+	return into, nil
+}
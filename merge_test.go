@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	memory := newTestStore(t,
+		Document{ID: 1, Name: "Report", Tags: []string{"draft", "finance"}},
+		Document{ID: 2, Name: "Report copy", File: "SGVsbG8=", Tags: []string{"finance", "q3"}},
+	)
+	data := mustExecute(t, newTestSchema(t), "mutation{merge(intoId:1,fromId:2){document{id,name,file,tags}}}", nil)
+	want := map[string]interface{}{"id": 1.0, "name": "Report", "file": "SGVsbG8=", "tags": []interface{}{"draft", "finance", "q3"}}
+	if got := lookup(data, "merge", "document"); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged document = %v, want %v", got, want)
+	}
+	if _, err := memory.Get(context.Background(), 2); !errors.Is(err, errNotFound) {
+		t.Errorf("Get(2) after merge: err = %v, want errNotFound", err)
+	}
+}
+
+func TestMergeTakesTheNameOfFromWithUniqueNames(t *testing.T) {
+	setVar(t, uniqueNames, true)
+	newTestStore(t, Document{ID: 1}, Document{ID: 2, Name: "Report"})
+	data := mustExecute(t, newTestSchema(t), "mutation{merge(intoId:1,fromId:2){document{id,name}}}", nil)
+	want := map[string]interface{}{"id": 1.0, "name": "Report"}
+	if got := lookup(data, "merge", "document"); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged document = %v, want %v", got, want)
+	}
+}
+
+// failingUpdateStore fails every update
+type failingUpdateStore struct {
+	Store
+}
+
+func (s failingUpdateStore) Update(ctx context.Context, document Document) (Document, error) {
+	return Document{}, errors.New("update failed")
+}
+
+func TestMergeKeepsBothDocumentsWhenTheUpdateFails(t *testing.T) {
+	memory := newTestStore(t, Document{ID: 1, Name: "Report"}, Document{ID: 2, Name: "Report copy"})
+	setVar(t, &store, Store(failingUpdateStore{memory}))
+	result := execute(context.Background(), newTestSchema(t), "mutation{merge(intoId:1,fromId:2){document{id}}}", nil)
+	if !result.HasErrors() {
+		t.Fatal("merge succeeded, want the update error")
+	}
+	if _, err := memory.Get(context.Background(), 2); err != nil {
+		t.Errorf("Get(2) after a failed merge: %v", err)
+	}
+}
+
+func TestMergeValidatesTheMergedDocument(t *testing.T) {
+	setVar(t, allowedExtensions, ".pdf")
+	memory := newTestStore(t, Document{ID: 1}, Document{ID: 2, Name: "notes.txt"})
+	result := execute(context.Background(), newTestSchema(t), "mutation{merge(intoId:1,fromId:2){document{id}}}", nil)
+	if !result.HasErrors() {
+		t.Fatal("merge into a .txt name succeeded, want an extension error")
+	}
+	if _, err := memory.Get(context.Background(), 2); err != nil {
+		t.Errorf("Get(2) after a rejected merge: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// documentMeta describes a document for clients discovering its fields
+type documentMeta struct {
+	ID         interface{} `json:"id"` // as formatted by formatID
+	Typename   string      `json:"typename"`
+	FieldNames []string    `json:"fieldNames"`
+}
+
+// jsonField is a field of Document as encoded in JSON
+type jsonField struct {
+	name      string
+	omitEmpty bool
+	field     reflect.StructField
+}
+
+// documentJSONFields returns the fields of Document encoded in JSON, named
+// by their JSON tags
+func documentJSONFields() []jsonField {
+	fields := []jsonField{}
+	documentStruct := reflect.TypeOf(Document{})
+	for i := 0; i < documentStruct.NumField(); i++ {
+		field := documentStruct.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{name: name, omitEmpty: strings.Contains(options, "omitempty"), field: field})
+	}
+	return fields
+}
+
+// documentFieldNames returns the names of the fields of Document, named as
+// configured by -snake-case
+func documentFieldNames() []string {
+	names := []string{}
+	for _, field := range documentJSONFields() {
+		name := field.name
+		if *snakeCaseFields {
+			name = snakeCase(name)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// jsonSchemaType returns the JSON Schema of values of type t
+func jsonSchemaType(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaType(t.Elem())}
+	}
+	return map[string]interface{}{}
+}
+
+// documentJSONSchema returns the JSON Schema of documents. Fields omitted
+// when empty are optional, the others required.
+func documentJSONSchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, field := range documentJSONFields() {
+		properties[field.name] = jsonSchemaType(field.field.Type)
+		if field.name == "id" && uuidMode() {
+			// Document.MarshalJSON writes ids as UUIDs
+			properties[field.name] = map[string]interface{}{"type": "string", "format": "uuid"}
+		}
+		if !field.omitEmpty {
+			required = append(required, field.name)
+		}
+	}
+	return map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      documentTypeName,
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// jsonSchemaHandler serves the JSON Schema of documents
+func jsonSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(documentJSONSchema())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocumentMeta(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	data := mustExecute(t, newTestSchema(t), "{documentMeta(id:1){id,typename,fieldNames}}", nil)
+	if got := lookup(data, "documentMeta", "id"); got != 1.0 {
+		t.Errorf("id = %v, want 1", got)
+	}
+	if got := lookup(data, "documentMeta", "typename"); got != "Document" {
+		t.Errorf("typename = %v, want Document", got)
+	}
+	// the JSON tags of Document, in order
+	want := "[id name file contentType tags ownerId storedSize version createdAt updatedAt]"
+	if got := fmt.Sprint(lookup(data, "documentMeta", "fieldNames")); got != want {
+		t.Errorf("fieldNames = %s, want %s", got, want)
+	}
+	if got := lookup(mustExecute(t, newTestSchema(t), "{documentMeta(id:99){id}}", nil), "documentMeta"); got != nil {
+		t.Errorf("documentMeta of a missing document = %v, want null", got)
+	}
+
+	setVar(t, snakeCaseFields, true)
+	data = mustExecute(t, newTestSchema(t), "{documentMeta(id:1){field_names}}", nil)
+	if got := fmt.Sprint(lookup(data, "documentMeta", "field_names")); got != "[id name file content_type tags owner_id stored_size version created_at updated_at]" {
+		t.Errorf("snake_case fieldNames = %s", got)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	w := serve(http.HandlerFunc(jsonSchemaHandler), httptest.NewRequest(http.MethodGet, "/schema.json", nil))
+	if got := w.Header().Get("Content-Type"); got != "application/schema+json" {
+		t.Errorf("content type %q, want application/schema+json", got)
+	}
+	schema := decodeResponse(t, w)
+	for _, field := range []string{"name", "file"} {
+		if got := lookup(schema, "properties", field, "type"); got != "string" {
+			t.Errorf("%s has type %v, want string", field, got)
+		}
+	}
+	if got := lookup(schema, "properties", "tags", "items", "type"); got != "string" {
+		t.Errorf("tags have items of type %v, want string", got)
+	}
+	if _, ok := lookup(schema, "properties").(map[string]interface{})["blobRef"]; ok {
+		t.Error("the schema has the internal blobRef")
+	}
+	// fields omitted when empty are optional
+	required := fmt.Sprint(schema["required"])
+	if !strings.Contains(required, "id") || strings.Contains(required, "file") {
+		t.Errorf("required %s, want id but not file", required)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+)
+
+// snakeCase converts a camelCase name to snake_case, e.g. "contentType" to
+// "content_type"
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// start a word after a lowercase letter or digit, or at the last
+			// letter of an acronym followed by a lowercase letter
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// objectFields returns the fields of an object type named as configured by
+// -snake-case. Renamed fields keep resolving the struct field of their
+// original name.
+func objectFields(fields graphql.Fields) graphql.Fields {
+	if !*snakeCaseFields {
+		return fields
+	}
+	renamed := graphql.Fields{}
+	for name, field := range fields {
+		original := name
+		resolve := field.Resolve
+		if resolve == nil {
+			resolve = graphql.DefaultResolveFn
+		}
+		field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+			p.Info.FieldName = original
+			return resolve(p)
+		}
+		renamed[snakeCase(name)] = field
+	}
+	return renamed
+}
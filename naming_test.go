@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"id":               "id",
+		"contentType":      "content_type",
+		"createdAt":        "created_at",
+		"fileDataUri":      "file_data_uri",
+		"clientMutationId": "client_mutation_id",
+		"HTTPServer":       "http_server",
+		"sha256":           "sha256",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSnakeCaseFields(t *testing.T) {
+	documents := testDocuments(1)
+	documents[0].ContentType = "text/plain"
+	newTestStore(t, documents...)
+	schema := newTestSchema(t)
+	fields := schema.Type(documentTypeName).(*graphql.Object).Fields()
+	if fields["contentType"] == nil || fields["content_type"] != nil {
+		t.Errorf("default field names %v", fields)
+	}
+
+	setVar(t, snakeCaseFields, true)
+	schema = newTestSchema(t)
+	fields = schema.Type(documentTypeName).(*graphql.Object).Fields()
+	if fields["content_type"] == nil || fields["contentType"] != nil || fields["created_at"] == nil {
+		t.Errorf("snake_case field names %v", fields)
+	}
+	// renamed fields resolve the struct field of their original name
+	data := mustExecute(t, schema, "{document(id:1){content_type,file_size}}", nil)
+	if got := lookup(data, "document", "content_type"); got != "text/plain" {
+		t.Errorf("content_type = %v, want text/plain", got)
+	}
+	if got := lookup(data, "document", "file_size"); got != 13.0 {
+		t.Errorf("file_size = %v, want 13", got)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// UserStore tells which users exist, so documents are only given to known
+// owners
+type UserStore interface {
+	UserExists(ctx context.Context, id int64) (bool, error)
+}
+
+// users validates the owners of documents. It is nil unless set, typically
+// from an init function in a file added to this package, in which case any
+// positive owner id is accepted.
+var users UserStore
+
+// checkOwner returns an error if documents can't be given to the owner
+// with the given id
+func checkOwner(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid owner id %d", id)
+	}
+	if users == nil {
+		return nil
+	}
+	exists, err := users.UserExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user %d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// knownUsers is a UserStore of a fixed set of users
+type knownUsers map[int64]bool
+
+func (u knownUsers) UserExists(ctx context.Context, id int64) (bool, error) {
+	return u[id], nil
+}
+
+func TestReassignOwner(t *testing.T) {
+	documents := testDocuments(3)
+	documents[0].OwnerID = 7
+	newTestStore(t, documents...)
+	setVar(t, &users, UserStore(knownUsers{7: true, 8: true}))
+	schema := newTestSchema(t)
+
+	data := mustExecute(t, schema, `mutation{reassignOwner(ids:[1,2],ownerId:8){count}}`, nil)
+	if got := lookup(data, "reassignOwner", "count"); got != 2.0 {
+		t.Errorf("count %v, want 2", got)
+	}
+	data = mustExecute(t, schema, `{list{id,ownerId}}`, nil)
+	for _, document := range data["list"].([]interface{}) {
+		want := 8.0
+		if lookup(document, "id") == 3.0 {
+			want = 0
+		}
+		if got := lookup(document, "ownerId"); got != want {
+			t.Errorf("document %v: owner %v, want %v", lookup(document, "id"), got, want)
+		}
+	}
+
+	result := execute(context.Background(), schema, `mutation{reassignOwner(ids:[1,3],ownerId:9){count}}`, nil)
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "user 9 not found") {
+		t.Errorf("unknown owner: errors %v, want user 9 not found", result.Errors)
+	}
+	if got := lookup(mustExecute(t, schema, `{document(id:3){ownerId}}`, nil), "document", "ownerId"); got != 0.0 {
+		t.Errorf("owner %v after the failed reassignment, want 0", got)
+	}
+}
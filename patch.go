@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches the header of a unified diff hunk, e.g. "@@ -1,3 +1,4 @@"
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// hunk is a change of a unified diff
+type hunk struct {
+	oldStart int
+	oldCount int
+	newCount int
+	lines    []string // lines prefixed with ' ', '-' or '+'
+	// set by "\ No newline at end of file" for the old and new sides
+	oldNoNewline bool
+	newNoNewline bool
+}
+
+// markNoNewline records a "\ No newline at end of file" line, which applies
+// to the line before it
+func (h *hunk) markNoNewline() {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1][0] {
+	case '-':
+		h.oldNoNewline = true
+	case '+':
+		h.newNoNewline = true
+	default:
+		h.oldNoNewline = true
+		h.newNoNewline = true
+	}
+}
+
+// parsePatch reads the hunks of a unified diff. File headers and other lines
+// outside of hunks are ignored.
+func parsePatch(patch string) ([]hunk, error) {
+	lines := strings.Split(patch, "\n")
+	hunks := []hunk{}
+	for i := 0; i < len(lines); i++ {
+		m := hunkHeader.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		h := hunk{oldCount: 1, newCount: 1}
+		h.oldStart, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			h.oldCount, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			h.newCount, _ = strconv.Atoi(m[4])
+		}
+
+		oldLeft, newLeft := h.oldCount, h.newCount
+		for (oldLeft > 0 || newLeft > 0) && i+1 < len(lines) {
+			i++
+			line := lines[i]
+			if strings.HasPrefix(line, `\`) {
+				h.markNoNewline()
+				continue
+			}
+			if line == "" {
+				// some tools strip the space of empty context lines
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				oldLeft--
+				newLeft--
+			case '-':
+				oldLeft--
+			case '+':
+				newLeft--
+			default:
+				return nil, fmt.Errorf("invalid patch line %q", line)
+			}
+			h.lines = append(h.lines, line)
+		}
+		if oldLeft > 0 || newLeft > 0 {
+			return nil, fmt.Errorf("patch hunk at line %d is truncated", h.oldStart)
+		}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+			h.markNoNewline()
+			i++
+		}
+		hunks = append(hunks, h)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch has no hunks")
+	}
+	return hunks, nil
+}
+
+// applyPatch applies a unified diff to text. It fails if the lines the patch
+// expects to keep or remove don't match the text.
+func applyPatch(text, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+
+	// lines added to an empty text end with a newline like any others
+	endsWithNewline := text == "" || strings.HasSuffix(text, "\n")
+	source := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		source = nil
+	}
+	result := []string{}
+	pos := 0
+	for n, h := range hunks {
+		start := h.oldStart - 1
+		if h.oldCount == 0 {
+			// pure insertions come after line oldStart
+			start = h.oldStart
+		}
+		if start < pos || start > len(source) {
+			return "", fmt.Errorf("patch does not apply: hunk %d starts at line %d", n+1, h.oldStart)
+		}
+		result = append(result, source[pos:start]...)
+		pos = start
+		for _, line := range h.lines {
+			op, content := line[0], line[1:]
+			if op == '+' {
+				result = append(result, content)
+				continue
+			}
+			if pos >= len(source) || source[pos] != content {
+				return "", fmt.Errorf("patch does not apply: hunk %d expects %q at line %d", n+1, content, pos+1)
+			}
+			if op == ' ' {
+				result = append(result, content)
+			}
+			pos++
+		}
+		if pos == len(source) {
+			if h.newNoNewline {
+				endsWithNewline = false
+			} else if h.oldNoNewline {
+				endsWithNewline = true
+			}
+		}
+	}
+	result = append(result, source[pos:]...)
+
+	patched := strings.Join(result, "\n")
+	if endsWithNewline && len(result) > 0 {
+		patched += "\n"
+	}
+	return patched, nil
+}
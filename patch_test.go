@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	text := "one\ntwo\nthree\n"
+	for _, test := range []struct {
+		patch string
+		want  string
+		err   string
+	}{
+		{"@@ -2 +2 @@\n-two\n+2\n", "one\n2\nthree\n", ""},
+		{"--- a\n+++ b\n@@ -1,3 +1,4 @@\n one\n+one and a half\n two\n three\n", "one\none and a half\ntwo\nthree\n", ""},
+		{"@@ -3 +3 @@\n-three\n+3\n\\ No newline at end of file\n", "one\ntwo\n3", ""},
+		{"@@ -2 +2 @@\n-deux\n+2\n", "", `hunk 1 expects "deux" at line 2`},
+		{"@@ -9 +9 @@\n-nine\n+9\n", "", "hunk 1 starts at line 9"},
+		{"not a patch", "", "no hunks"},
+	} {
+		got, err := applyPatch(text, test.patch)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("applyPatch(%q) = %q, %v, want an error with %q", test.patch, got, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("applyPatch(%q) = %q, %v, want %q", test.patch, got, err, test.want)
+		}
+	}
+}
+
+func TestPatchFile(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Notes", File: base64.StdEncoding.EncodeToString([]byte("old\n"))})
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation($patch:String!){patchFile(id:1,patch:$patch){document{file}}}`, map[string]interface{}{"patch": "@@ -1 +1 @@\n-old\n+new\n"})
+	if got := lookup(data, "patchFile", "document", "file"); got != base64.StdEncoding.EncodeToString([]byte("new\n")) {
+		t.Errorf("patched file = %v, want new", got)
+	}
+	// the same patch no longer applies
+	result := execute(context.Background(), schema, `mutation($patch:String!){patchFile(id:1,patch:$patch){document{file}}}`, map[string]interface{}{"patch": "@@ -1 +1 @@\n-old\n+new\n"})
+	if !result.HasErrors() {
+		t.Error("conflicting patch applied")
+	}
+}
+
+func TestPatchFields(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Draft", Tags: []string{"draft"}, ContentType: "text/plain"})
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{patch(id:1,fields:{name:"Report",tags:["report","2021"]}){document{name,tags,contentType}}}`, nil)
+	if got := fmt.Sprint(lookup(data, "patch", "document")); got != "map[contentType:text/plain name:Report tags:[report 2021]]" {
+		t.Errorf("patched %s, want the name and tags changed", got)
+	}
+
+	// null clears a field, sent in variables
+	data = mustExecute(t, schema, `mutation($fields:JSON!){patch(id:1,fields:$fields){document{contentType}}}`, map[string]interface{}{
+		"fields": map[string]interface{}{"contentType": nil},
+	})
+	if got := lookup(data, "patch", "document", "contentType"); got != "" {
+		t.Errorf("content type %q, want it cleared", got)
+	}
+
+	for _, query := range []string{
+		`mutation{patch(id:1,fields:{name:"Other",owner:"alice"}){document{id}}}`,
+		`mutation{patch(id:1,fields:{name:"Other",tags:"report"}){document{id}}}`,
+	} {
+		if result := execute(context.Background(), schema, query, nil); !result.HasErrors() {
+			t.Errorf("%s succeeded", query)
+		}
+	}
+	if got := lookup(mustExecute(t, schema, `{document(id:1){name}}`, nil), "document", "name"); got != "Report" {
+		t.Errorf("name %v after the rejected patches, want Report", got)
+	}
+	result := execute(context.Background(), schema, `mutation{patch(id:1,fields:{owner:"alice"}){document{id}}}`, nil)
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, `unknown field "owner"`) {
+		t.Errorf("errors %v, want the unknown field", result.Errors)
+	}
+}
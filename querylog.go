@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// redacted replaces the values kept out of the query log
+const redacted = "<redacted>"
+
+// logQuery logs the query of req, with its variables redacted
+func logQuery(ctx context.Context, req graphqlRequest) {
+	slog.Info("query",
+		"requestId", requestIDFrom(ctx),
+		"path", req.path,
+		"operationName", req.OperationName,
+		"query", req.Query,
+		"variables", redactVariables(req.Variables),
+	)
+}
+
+// redactVariables returns a copy of variables with the values of the keys
+// listed in -redact-keys, and strings longer than -redact-length, replaced
+func redactVariables(variables map[string]interface{}) map[string]interface{} {
+	keys := map[string]bool{}
+	for _, key := range splitList(*redactKeys) {
+		keys[strings.ToLower(key)] = true
+	}
+	redactedVariables, _ := redactValue(variables, keys).(map[string]interface{})
+	return redactedVariables
+}
+
+func redactValue(value interface{}, keys map[string]bool) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value == nil {
+			return nil
+		}
+		copied := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if keys[strings.ToLower(key)] {
+				copied[key] = redacted
+				continue
+			}
+			copied[key] = redactValue(item, keys)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = redactValue(item, keys)
+		}
+		return copied
+	case string:
+		if *redactLength > 0 && len(value) > *redactLength {
+			return redacted
+		}
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryLogRedactsFiles(t *testing.T) {
+	var output bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	setVar(t, logQueries, true)
+	newTestStore(t)
+	handler := documentHandler(newTestSchema(t))
+
+	file := strings.Repeat("QUJD", 100)
+	query := `mutation($name:String!,$file:String){create(name:$name,file:$file){document{id}}}`
+	body, _ := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": map[string]interface{}{"name": "Report", "file": file},
+	})
+	r := httptest.NewRequest(http.MethodPost, "/document", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	serve(handler, r)
+
+	var logged struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &logged); err != nil {
+		t.Fatalf("log %q: %v", output.String(), err)
+	}
+	if logged.Query != query {
+		t.Errorf("logged query %q, want %q", logged.Query, query)
+	}
+	if logged.Variables["file"] != redacted || logged.Variables["name"] != "Report" {
+		t.Errorf("logged variables %v, want the file redacted", logged.Variables)
+	}
+	if strings.Contains(output.String(), file) {
+		t.Error("the log has the file")
+	}
+}
+
+func TestRedactVariables(t *testing.T) {
+	setVar(t, redactKeys, "password")
+	setVar(t, redactLength, 8)
+	got := redactVariables(map[string]interface{}{
+		"input":    map[string]interface{}{"Password": "x", "name": "short"},
+		"tags":     []interface{}{"a", "much too long"},
+		"password": "hunter2",
+		"limit":    10.0,
+	})
+	want := map[string]interface{}{
+		"input":    map[string]interface{}{"Password": redacted, "name": "short"},
+		"tags":     []interface{}{"a", redacted},
+		"password": redacted,
+		"limit":    10.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redacted %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng is the random number generator of the server, used for random ids,
+// randomDocument and retry jitter. It is seeded from the time, unless
+// seedRandom reseeds it for a reproducible run.
+var rng = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// seedRandom reseeds rng, so that it gives the same numbers again
+func seedRandom(seed int64) {
+	rng.Lock()
+	defer rng.Unlock()
+	rng.Rand = rand.New(rand.NewSource(seed))
+}
+
+// randomInt63n returns a random number in [0, n) from rng
+func randomInt63n(n int64) int64 {
+	rng.Lock()
+	defer rng.Unlock()
+	return rng.Int63n(n)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomDocumentIsReproducible(t *testing.T) {
+	newTestStore(t, testDocuments(20)...)
+	schema := newTestSchema(t)
+	t.Cleanup(func() { seedRandom(time.Now().UnixNano()) })
+	randomIDs := func() []interface{} {
+		seedRandom(42)
+		var ids []interface{}
+		for i := 0; i < 10; i++ {
+			ids = append(ids, lookup(mustExecute(t, schema, "{randomDocument{id}}", nil), "randomDocument", "id"))
+		}
+		return ids
+	}
+	first := randomIDs()
+	seen := map[interface{}]bool{}
+	for i, id := range randomIDs() {
+		if id != first[i] {
+			t.Fatalf("random document %d = %v, want %v as with the same seed before", i, id, first[i])
+		}
+		seen[id] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("random documents %v, want several documents", first)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// documentTypeName is the GraphQL type name of documents, and the prefix of
+// their global ids
+const documentTypeName = "Document"
+
+// globalID returns the Relay global id of an object: its type name and id,
+// base64 encoded
+func globalID(typeName string, id int64) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", typeName, id)))
+}
+
+// fromGlobalID is the reverse of globalID
+func fromGlobalID(globalID string) (string, int64, error) {
+	decoded, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid global id %q", globalID)
+	}
+	typeName, rawID, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid global id %q", globalID)
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid global id %q", globalID)
+	}
+	return typeName, id, nil
+}
+
+// resolveNode returns the object with the given global id, or nil if there
+// is none
+func resolveNode(ctx context.Context, id string) (interface{}, error) {
+	typeName, localID, err := fromGlobalID(id)
+	if err != nil {
+		return nil, err
+	}
+	switch typeName {
+	case documentTypeName:
+		document, err := store.Get(ctx, localID)
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return document, err
+	}
+	return nil, nil
+}
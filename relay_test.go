@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGlobalID(t *testing.T) {
+	id := globalID(documentTypeName, 42)
+	if id != "RG9jdW1lbnQ6NDI=" { // Document:42
+		t.Errorf("globalID = %q", id)
+	}
+	if typeName, parsed, err := fromGlobalID(id); err != nil || typeName != documentTypeName || parsed != 42 {
+		t.Errorf("fromGlobalID(%q) = %q, %d, %v", id, typeName, parsed, err)
+	}
+	for _, invalid := range []string{"not base64!", "RG9jdW1lbnQ=", "RG9jdW1lbnQ6eA=="} {
+		if _, _, err := fromGlobalID(invalid); err == nil {
+			t.Errorf("fromGlobalID(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestNode(t *testing.T) {
+	setVar(t, relay, true)
+	newTestStore(t, testDocuments(2)...)
+	schema := newTestSchema(t)
+	id := globalID(documentTypeName, 2)
+	data := mustExecute(t, schema, `query($id:ID!){node(id:$id){id,...on Document{name}}}`, map[string]interface{}{"id": id})
+	if got := lookup(data, "node", "id"); got != id {
+		t.Errorf("node id = %v, want %v", got, id)
+	}
+	if got := lookup(data, "node", "name"); got != "Document 2" {
+		t.Errorf("node name = %v, want Document 2", got)
+	}
+
+	data = mustExecute(t, schema, `query($ids:[ID!]!){nodes(ids:$ids){id}}`, map[string]interface{}{
+		"ids": []interface{}{globalID(documentTypeName, 1), globalID(documentTypeName, 99)},
+	})
+	nodes, _ := data["nodes"].([]interface{})
+	if len(nodes) != 2 || lookup(nodes[0], "id") != globalID(documentTypeName, 1) || nodes[1] != nil {
+		t.Errorf("nodes = %v, want document 1 and null", nodes)
+	}
+
+	if result := execute(context.Background(), schema, `{node(id:"bad"){id}}`, nil); !result.HasErrors() {
+		t.Error("node with an invalid id succeeded")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	handler := withRequestID(documentHandler(newTestSchema(t)))
+	request := func(id string) (string, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodGet, "/document?query={document(id:1){id}}", nil)
+		if id != "" {
+			r.Header.Set(requestIDHeader, id)
+		}
+		w := serve(handler, r)
+		return w.Header().Get(requestIDHeader), decodeResponse(t, w)
+	}
+
+	header, body := request("abc-123")
+	if header != "abc-123" || lookup(body, "extensions", "requestId") != "abc-123" {
+		t.Errorf("incoming id echoed as %q and %v, want abc-123", header, lookup(body, "extensions", "requestId"))
+	}
+	for _, incoming := range []string{"", "bad\nid", strings.Repeat("a", maxRequestIDLength+1)} {
+		header, body = request(incoming)
+		if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(header) || lookup(body, "extensions", "requestId") != header {
+			t.Errorf("request with id %q: got %q and %v, want a generated id", incoming, header, lookup(body, "extensions", "requestId"))
+		}
+	}
+	if first, _ := request(""); first == header {
+		t.Errorf("generated id %q twice", first)
+	}
+}
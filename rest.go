@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// restDocumentPath is the path of a document in the REST API
+func restDocumentPath(id int64) string {
+	return fmt.Sprintf("/api/documents/%s", idString(id))
+}
+
+// restStatus returns the status of a REST response failing with err
+func restStatus(err error) int {
+	var conflict *conflictError
+	var internal *internalError
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &conflict):
+		return http.StatusConflict
+	case errors.Is(err, errUnavailable), errors.Is(err, errBusy):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &internal):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// restError writes err as the plain text response of a REST request
+func restError(w http.ResponseWriter, r *http.Request, err error) {
+	message, _ := safeMessage(r.Context(), err)
+	http.Error(w, message, restStatus(err))
+}
+
+// writeDocument writes document as the JSON response of a REST request,
+// with its file loaded if the client may read it
+func writeDocument(w http.ResponseWriter, r *http.Request, status int, document Document) {
+	if canReadFiles(r.Context()) {
+		var err error
+		if document, err = withFile(document); err != nil {
+			restError(w, r, err)
+			return
+		}
+	} else {
+		document.File = ""
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(document)
+}
+
+// restCreateHandler creates a document from the JSON object of the body, as
+// create does, and responds with a 201 and its location
+func restCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if *readOnly {
+		http.Error(w, "mutations disabled", http.StatusForbidden)
+		return
+	}
+	if mutations != nil {
+		if err := mutations.acquire(r.Context()); err != nil {
+			w.Header().Set("Retry-After", "1")
+			restError(w, r, err)
+			return
+		}
+		defer mutations.release()
+	}
+	var fields importedDocument
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		http.Error(w, "the body must be a JSON document", http.StatusBadRequest)
+		return
+	}
+	document, err := fields.create(r.Context())
+	if err != nil {
+		restError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", restDocumentPath(document.ID))
+	writeDocument(w, r, http.StatusCreated, document)
+}
+
+// restGetHandler responds with the document at a location given by
+// restCreateHandler
+func restGetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDString(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid document id", http.StatusBadRequest)
+		return
+	}
+	document, err := store.Get(r.Context(), id)
+	if err != nil {
+		restError(w, r, err)
+		return
+	}
+	writeDocument(w, r, http.StatusOK, document)
+}
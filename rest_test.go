@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRestCreate(t *testing.T) {
+	newTestStore(t, testDocuments(3)...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/documents", restCreateHandler)
+	mux.HandleFunc("GET /api/documents/{id}", restGetHandler)
+
+	w := serve(mux, httptest.NewRequest(http.MethodPost, "/api/documents", strings.NewReader(`{"name":"Report","file":"SGVsbG8="}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if location != "/api/documents/4" {
+		t.Errorf("Location = %q, want /api/documents/4", location)
+	}
+	if created := decodeResponse(t, w); created["id"] != 4.0 || created["name"] != "Report" {
+		t.Errorf("created document = %v", created)
+	}
+
+	w = serve(mux, httptest.NewRequest(http.MethodGet, location, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d, want 200", location, w.Code)
+	}
+	if got := decodeResponse(t, w); got["name"] != "Report" || got["file"] != "SGVsbG8=" {
+		t.Errorf("GET %s = %v", location, got)
+	}
+}
+
+func TestRestCreateRunsMutationHooks(t *testing.T) {
+	memory := newTestStore(t)
+	setVar(t, &mutationHooks, nil)
+	RegisterMutationHook(func(ctx context.Context, operation string, args map[string]interface{}) error {
+		if name, _ := args["name"].(string); operation == "create" && strings.HasPrefix(name, "tmp") {
+			return errors.New("temporary documents are not allowed")
+		}
+		return nil
+	})
+	w := serve(http.HandlerFunc(restCreateHandler), httptest.NewRequest(http.MethodPost, "/api/documents", strings.NewReader(`{"name":"tmp report"}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "temporary documents are not allowed") {
+		t.Errorf("response = %d %q, want the error of the hook", w.Code, w.Body.String())
+	}
+	if documents, _ := memory.List(context.Background()); len(documents) != 0 {
+		t.Errorf("documents created despite the hook: %v", documents)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// retryStore retries the operations of a store failing with a transient
+// error, with exponential backoff and jitter
+type retryStore struct {
+	Store
+	attempts int
+	backoff  time.Duration // before the second attempt, doubled after each
+}
+
+// retryable reports whether err is transient, so the operation may succeed
+// if tried again
+func retryable(err error) bool {
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR)
+}
+
+// retry runs op until it succeeds, fails with an error which isn't
+// retryable, or runs out of attempts. It doesn't wait past the deadline of
+// ctx.
+func (s retryStore) retry(ctx context.Context, op func() error) error {
+	backoff := s.backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= s.attempts || !retryable(err) {
+			return err
+		}
+		// wait between half and all of the backoff, so clients failing
+		// together don't retry together
+		wait := backoff/2 + time.Duration(randomInt63n(int64(backoff/2)+1))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (s retryStore) List(ctx context.Context) (documents []Document, err error) {
+	err = s.retry(ctx, func() error {
+		documents, err = s.Store.List(ctx)
+		return err
+	})
+	return documents, err
+}
+
+func (s retryStore) ListSorted(ctx context.Context, field string) (documents []Document, err error) {
+	err = s.retry(ctx, func() error {
+		documents, err = s.Store.ListSorted(ctx, field)
+		return err
+	})
+	return documents, err
+}
+
+func (s retryStore) Get(ctx context.Context, id int64) (document Document, err error) {
+	err = s.retry(ctx, func() error {
+		document, err = s.Store.Get(ctx, id)
+		return err
+	})
+	return document, err
+}
+
+func (s retryStore) Search(ctx context.Context, term string) (documents []Document, err error) {
+	err = s.retry(ctx, func() error {
+		documents, err = s.Store.Search(ctx, term)
+		return err
+	})
+	return documents, err
+}
+
+func (s retryStore) NextID(ctx context.Context) (id int64, err error) {
+	err = s.retry(ctx, func() error {
+		id, err = s.Store.NextID(ctx)
+		return err
+	})
+	return id, err
+}
+
+// Create is retried like the other operations: the store only fails
+// before storing the document, so retrying never creates it twice
+func (s retryStore) Create(ctx context.Context, document Document) (created Document, err error) {
+	err = s.retry(ctx, func() error {
+		created, err = s.Store.Create(ctx, document)
+		return err
+	})
+	return created, err
+}
+
+func (s retryStore) Update(ctx context.Context, document Document) (updated Document, err error) {
+	err = s.retry(ctx, func() error {
+		updated, err = s.Store.Update(ctx, document)
+		return err
+	})
+	return updated, err
+}
+
+func (s retryStore) Delete(ctx context.Context, id int64) (document Document, err error) {
+	err = s.retry(ctx, func() error {
+		document, err = s.Store.Delete(ctx, id)
+		return err
+	})
+	return document, err
+}
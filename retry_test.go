@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyStore fails the first gets with err
+type flakyStore struct {
+	Store
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyStore) Get(ctx context.Context, id int64) (Document, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return Document{}, s.err
+	}
+	return s.Store.Get(ctx, id)
+}
+
+func TestRetryStore(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		attempts int
+		err      error
+		calls    int
+		fails    bool
+	}{
+		{"succeeds on the third attempt", 3, syscall.ECONNRESET, 3, false},
+		{"runs out of attempts", 2, syscall.ECONNRESET, 2, true},
+		{"does not retry other errors", 3, errors.New("broken"), 1, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			flaky := &flakyStore{Store: newTestStore(t, testDocuments(1)...), failures: 2, err: test.err}
+			retrying := retryStore{Store: flaky, attempts: test.attempts, backoff: time.Millisecond}
+			document, err := retrying.Get(context.Background(), 1)
+			if test.fails != (err != nil) {
+				t.Errorf("Get = %v, %v", document, err)
+			}
+			if !test.fails && document.ID != 1 {
+				t.Errorf("Get = document %d, want 1", document.ID)
+			}
+			if flaky.calls != test.calls {
+				t.Errorf("%d attempts, want %d", flaky.calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestRetryStoreStopsAtTheDeadline(t *testing.T) {
+	flaky := &flakyStore{Store: newTestStore(t, testDocuments(1)...), failures: 2, err: syscall.ECONNRESET}
+	retrying := retryStore{Store: flaky, attempts: 3, backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := retrying.Get(ctx, 1); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("Get = %v, want the error of the first attempt", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("%d attempts, want 1", flaky.calls)
+	}
+}
@@ -0,0 +1,1470 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+func newDocumentType(version schemaVersion) *graphql.Object {
+	var documentType *graphql.Object
+	idField := &graphql.Field{
+		Type: documentIDType(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			document, _ := p.Source.(Document)
+			return formatID(document.ID), nil
+		},
+	}
+	var interfaces []*graphql.Interface
+	if *relay {
+		// Relay identifies documents by their global id
+		idField = &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				document, _ := p.Source.(Document)
+				return globalID(documentTypeName, document.ID), nil
+			},
+		}
+		interfaces = append(interfaces, graphql.NewInterface(graphql.InterfaceConfig{
+			Name:        "Node",
+			Description: "An object with a global id",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+			},
+			ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+				if _, ok := p.Value.(Document); ok {
+					return documentType
+				}
+				return nil
+			},
+		}))
+	}
+
+	documentType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:       documentTypeName,
+			Interfaces: interfaces,
+			Fields: objectFields(version.selectFields(documentTypeName, graphql.Fields{
+				"id": idField,
+				"name": &graphql.Field{
+					Type: graphql.String,
+				},
+				"file": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Base64 encoded content of the file, null for clients lacking the scope of -file-scope",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if !canReadFiles(p.Context) {
+							return nil, nil
+						}
+						document, err := withFile(p.Source.(Document))
+						return document.File, err
+					},
+				},
+				"fileDataUri": &graphql.Field{
+					Type:        graphql.String,
+					Description: "File as a data: URI with its content type, for embedding in HTML; null for empty files and for clients lacking the scope of -file-scope",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if !canReadFiles(p.Context) {
+							return nil, nil
+						}
+						uri, err := fileDataURI(p.Source.(Document))
+						if uri == "" || err != nil {
+							return nil, err
+						}
+						return uri, nil
+					},
+				},
+				"contentType": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Media type of the file",
+				},
+				"tags": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+				},
+				"ownerId": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Id of the user owning the document, if any",
+				},
+				"fileSize": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Size in bytes of the decoded file",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						document, err := withFile(p.Source.(Document))
+						return document.FileSize(), err
+					},
+				},
+				"fileSizeHuman": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Size of the decoded file for people, e.g. \"1.2 MB\"",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						document, err := withFile(p.Source.(Document))
+						return humanSize(document.FileSize()), err
+					},
+				},
+				"storedSize": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Size in bytes of the file as stored, after compression",
+				},
+				"version": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "Incremented on every update",
+				},
+				"createdAt": &graphql.Field{
+					Type: graphql.DateTime,
+				},
+				"updatedAt": &graphql.Field{
+					Type: graphql.DateTime,
+				},
+			})),
+		},
+	)
+	return documentType
+}
+
+func newQueryType(version schemaVersion, documentType *graphql.Object) *graphql.Object {
+	fields := graphql.Fields{
+		/* Get (read) single document by id
+		   http://localhost:8080/document?query={document(id:1){name,file}}
+		*/
+		"document": &graphql.Field{
+			Type:        documentType,
+			Description: "Get document by id",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: documentIDType(),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if value, ok := p.Args["id"]; ok {
+					id, err := documentID(value)
+					if err != nil {
+						return nil, err
+					}
+					// Find document, batched with the other lookups of the query
+					load := documentLoaderFrom(p.Context).load(p.Context, id)
+					return func() (interface{}, error) {
+						document, err := load()
+						if err != nil {
+							return missingDocument(p, id, err)
+						}
+						return document, nil
+					}, nil
+				}
+				return nil, nil
+			},
+		},
+		/* Get (read) documents list
+		   http://localhost:8080/document?query={list{id,name,file}}
+		   http://localhost:8080/document?query={list(createdAfter:"2021-01-01T00:00:00Z"){id,name,createdAt}}
+		   http://localhost:8080/document?query={list(limit:10,offset:20){id,name}}
+		   http://localhost:8080/document?query={list(sortBy:"NAME"){id,name}}
+		   http://localhost:8080/document?query={list(sortBy:"NAME",descending:true){id,name}}
+		   http://localhost:8080/document?query={list(hasFile:false){id,name}}
+		*/
+		"list": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Get document list",
+			Args: graphql.FieldConfigArgument{
+				"createdAfter": &graphql.ArgumentConfig{
+					Type:        graphql.DateTime,
+					Description: "Only documents created after this time",
+				},
+				"createdBefore": &graphql.ArgumentConfig{
+					Type:        graphql.DateTime,
+					Description: "Only documents created before this time",
+				},
+				"hasFile": &graphql.ArgumentConfig{
+					Type:        graphql.Boolean,
+					Description: "Only documents with a file if true, without one if false",
+				},
+				"sortBy": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Field to sort by: ID or NAME. Without sortBy and descending, lists are sorted by " + defaultSortOrder.String(),
+				},
+				"descending": &graphql.ArgumentConfig{
+					Type:        graphql.Boolean,
+					Description: "Whether to sort from the last document to the first; false if sortBy is set",
+				},
+				"limit": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum number of documents to return, capped by the server's maximum page size",
+				},
+				"offset": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Number of documents to skip",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				// the order of -default-sort only applies as a whole
+				order := defaultSortOrder
+				if sortBy, ok := params.Args["sortBy"].(string); ok {
+					if err := checkSortField(sortBy); err != nil {
+						return nil, err
+					}
+					order = sortOrder{field: sortBy}
+				}
+				if descending, ok := params.Args["descending"].(bool); ok {
+					order.descending = descending
+				}
+				documents, err := listDocuments(params.Context, order.field)
+				if err != nil {
+					return nil, err
+				}
+				if order.descending {
+					reverseDocuments(documents)
+				}
+				documents = newDocumentFilter(params.Args).apply(documents)
+				documents, err = paginate(documents, params.Args)
+				if err != nil {
+					return nil, err
+				}
+				return streamItems(params, documents), nil
+			},
+		},
+		/* Get (read) documents by name, a page at a time: pass the name of the
+		   last document of a page as afterName to get the next one
+		   http://localhost:8080/document?query={listByName(limit:10){id,name}}
+		   http://localhost:8080/document?query={listByName(afterName:"Document 2",afterId:2,limit:10){id,name}}
+		*/
+		"listByName": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Get documents sorted by name, starting after a name",
+			Args: graphql.FieldConfigArgument{
+				"afterName": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Only documents whose name sorts strictly after this one; all documents if not set",
+				},
+				"afterId": &graphql.ArgumentConfig{
+					Type:        documentIDType(),
+					Description: "Id of the last document of the previous page, so that documents named afterName with a higher id are included",
+				},
+				"limit": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum number of documents to return, capped by the server's maximum page size",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := listDocuments(params.Context, "name")
+				if err != nil {
+					return nil, err
+				}
+				if afterName, ok := params.Args["afterName"].(string); ok {
+					afterID := int64(math.MaxInt64)
+					if value, ok := params.Args["afterId"]; ok {
+						if afterID, err = documentID(value); err != nil {
+							return nil, err
+						}
+					}
+					documents = namesAfter(documents, afterName, afterID)
+				}
+				return paginate(documents, params.Args)
+			},
+		},
+		/* Get the documents updated last, e.g. for an activity feed
+		   http://localhost:8080/document?query={recentlyUpdated(limit:5){id,name,updatedAt}}
+		*/
+		"recentlyUpdated": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Get the documents updated last, most recent first",
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: 10,
+					Description:  "Maximum number of documents to return, capped by the server's maximum page size",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				sortRecentlyUpdated(documents)
+				return paginate(documents, params.Args)
+			},
+		},
+		/* Get (read) several documents by id, in the requested order
+		   http://localhost:8080/document?query={documentsByIds(ids:[1,3]){id,name,file}}
+		*/
+		"documentsByIds": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Get documents by ids; missing documents are null unless omitMissing is set",
+			Args: graphql.FieldConfigArgument{
+				"ids": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(documentIDType()))),
+				},
+				"omitMissing": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				values, _ := params.Args["ids"].([]interface{})
+				ids, err := documentIDs(values)
+				if err != nil {
+					return nil, err
+				}
+				omitMissing, _ := params.Args["omitMissing"].(bool)
+				result := []interface{}{}
+				for _, id := range ids {
+					document, err := store.Get(params.Context, id)
+					switch {
+					case errors.Is(err, errNotFound):
+						if !omitMissing {
+							result = append(result, nil)
+						}
+					case err != nil:
+						return nil, err
+					default:
+						result = append(result, document)
+					}
+				}
+				return result, nil
+			},
+		},
+		/* Search documents by the words of their name, most relevant first
+		   http://localhost:8080/document?query={search(term:"document"){id,name}}
+		*/
+		"search": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Search documents by name, most relevant first",
+			Args: graphql.FieldConfigArgument{
+				"term": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				term, _ := params.Args["term"].(string)
+				return store.Search(params.Context, term)
+			},
+		},
+		/* Get the tags in use and the number of documents having them, most used first
+		   http://localhost:8080/document?query={tags{tag,count}}
+		*/
+		"tags": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "TagCount",
+				Fields: objectFields(graphql.Fields{
+					"tag": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type: graphql.Int,
+					},
+				}),
+			})),
+			Description: "Get the tags in use with their number of documents, most used first",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				return countTags(documents), nil
+			},
+		},
+		/* Get statistics over all documents
+		   http://localhost:8080/document?query={stats{totalDocuments,totalFileBytes,averageFileBytes,documentsWithFile,documentsWithoutFile}}
+		*/
+		"stats": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "DocumentStats",
+				Fields: objectFields(graphql.Fields{
+					"totalDocuments": &graphql.Field{
+						Type: graphql.Int,
+					},
+					"totalFileBytes": &graphql.Field{
+						Type:        graphql.Int,
+						Description: "Sum of the sizes in bytes of the decoded files",
+					},
+					"averageFileBytes": &graphql.Field{
+						Type:        graphql.Float,
+						Description: "Average size in bytes of the decoded files of the documents having one",
+					},
+					"documentsWithFile": &graphql.Field{
+						Type: graphql.Int,
+					},
+					"documentsWithoutFile": &graphql.Field{
+						Type: graphql.Int,
+					},
+				}),
+			}),
+			Description: "Get statistics over all documents",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				return computeStats(documents)
+			},
+		},
+		/* Get the documents without a content type, e.g. to backfill it
+		   http://localhost:8080/document?query={documentsMissingContentType{id,name}}
+		*/
+		"documentsMissingContentType": &graphql.Field{
+			Type:        graphql.NewList(documentType),
+			Description: "Get the documents without a content type, sorted by id",
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Maximum number of documents to return, capped by the server's maximum page size",
+				},
+				"offset": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Number of documents to skip",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := listDocuments(params.Context, "id")
+				if err != nil {
+					return nil, err
+				}
+				missing := []Document{}
+				for _, document := range documents {
+					if document.ContentType == "" {
+						missing = append(missing, document)
+					}
+				}
+				return paginate(missing, params.Args)
+			},
+		},
+		/* Get the number of documents and total file size by content type, most documents first
+		   http://localhost:8080/document?query={byContentType{contentType,count,totalBytes}}
+		*/
+		"byContentType": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "ContentTypeCount",
+				Fields: objectFields(graphql.Fields{
+					"contentType": &graphql.Field{
+						Type:        graphql.String,
+						Description: "Media type of the files, \"unknown\" for documents without one",
+					},
+					"count": &graphql.Field{
+						Type: graphql.Int,
+					},
+					"totalBytes": &graphql.Field{
+						Type:        graphql.Int,
+						Description: "Sum of the sizes in bytes of the decoded files",
+					},
+				}),
+			})),
+			Description: "Get the number of documents and total file size by content type, most documents first",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				return countContentTypes(documents)
+			},
+		},
+		/* Get the number of create, update and delete mutations since the server started
+		   http://localhost:8080/document?query={serverStats{creates,updates,deletes}}
+		*/
+		"serverStats": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "ServerStats",
+				Fields: objectFields(graphql.Fields{
+					"creates": &graphql.Field{
+						Type: graphql.Int,
+					},
+					"updates": &graphql.Field{
+						Type:        graphql.Int,
+						Description: "Documents changed by any mutation other than create and delete, e.g. patch or renameTag",
+					},
+					"deletes": &graphql.Field{
+						Type: graphql.Int,
+					},
+				}),
+			}),
+			Description: "Get the number of documents created, updated and deleted since the server started",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				// the counts change with every mutation, like lists
+				trackAllReads(params.Context)
+				return counters.snapshot(), nil
+			},
+		},
+		/* Compare two documents
+		   http://localhost:8080/document?query={diff(aId:1,bId:2){field,aValue,bValue}}
+		*/
+		"diff": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "FieldDiff",
+				Fields: objectFields(graphql.Fields{
+					"field": &graphql.Field{
+						Type: graphql.String,
+					},
+					"aValue": &graphql.Field{
+						Type: graphql.String,
+					},
+					"bValue": &graphql.Field{
+						Type: graphql.String,
+					},
+				}),
+			})),
+			Description: "Get the fields differing between two documents: name, contentType, tags and hasFile",
+			Args: graphql.FieldConfigArgument{
+				"aId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"bId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents := make([]Document, 2)
+				for i, arg := range []string{"aId", "bId"} {
+					id, err := documentID(params.Args[arg])
+					if err != nil {
+						return nil, err
+					}
+					document, err := store.Get(params.Context, id)
+					// there is nothing to compare with a missing document
+					if errors.Is(err, errNotFound) {
+						return nil, &documentNotFoundError{id: id}
+					}
+					if err != nil {
+						return nil, err
+					}
+					documents[i] = document
+				}
+				return diffDocuments(documents[0], documents[1]), nil
+			},
+		},
+		/* Get a random document
+		   http://localhost:8080/document?query={randomDocument{id,name}}
+		*/
+		"randomDocument": &graphql.Field{
+			Type:        documentType,
+			Description: "Get a random document, or null if there are none",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				// the same documents give another result on each run
+				markUncacheable(params.Context)
+				documents, err := store.List(params.Context)
+				if err != nil || len(documents) == 0 {
+					return nil, err
+				}
+				return documents[randomInt63n(int64(len(documents)))], nil
+			},
+		},
+		/* Get the type name and field names of a document
+		   http://localhost:8080/document?query={documentMeta(id:1){id,typename,fieldNames}}
+		*/
+		"documentMeta": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "DocumentMeta",
+				Fields: objectFields(graphql.Fields{
+					"id": &graphql.Field{
+						Type: documentIDType(),
+					},
+					"typename": &graphql.Field{
+						Type: graphql.String,
+					},
+					"fieldNames": &graphql.Field{
+						Type: graphql.NewList(graphql.String),
+					},
+				}),
+			}),
+			Description: "Get the type name and field names of a document, or null if there is none",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				return documentMeta{
+					ID:         formatID(document.ID),
+					Typename:   documentTypeName,
+					FieldNames: documentFieldNames(),
+				}, nil
+			},
+		},
+		/* Check the SHA-256 of the file of a document, e.g. after downloading it
+		   http://localhost:8080/document?query={verifyFile(id:1,sha256:"dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f"){matches,sha256}}
+		*/
+		"verifyFile": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "FileVerification",
+				Fields: objectFields(graphql.Fields{
+					"matches": &graphql.Field{
+						Type:        graphql.Boolean,
+						Description: "Whether the file has the given hash",
+					},
+					"sha256": &graphql.Field{
+						Type:        graphql.String,
+						Description: "Hex SHA-256 of the decoded file",
+					},
+				}),
+			}),
+			Description: "Check the file of a document against a SHA-256, or null if there is no document",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"sha256": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "Expected hex SHA-256 of the decoded file",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				sum, _ := params.Args["sha256"].(string)
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				return verifyFile(document, sum)
+			},
+		},
+		/* Get the id the next created document will get
+		   http://localhost:8080/document?query={nextId}
+		*/
+		"nextId": &graphql.Field{
+			Type:        documentIDType(),
+			Description: "Get the id the next created document will get; advisory only, as a concurrent create may take it first",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := store.NextID(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				return formatID(id), nil
+			},
+		},
+	}
+	if *relay {
+		node := documentType.Interfaces()[0]
+		/* Get (read) any node by its global id
+		   http://localhost:8080/document?query={node(id:"RG9jdW1lbnQ6MQ=="){id,...on+Document{name}}}
+		*/
+		fields["node"] = &graphql.Field{
+			Type:        node,
+			Description: "Get node by global id",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, _ := params.Args["id"].(string)
+				return resolveNode(params.Context, id)
+			},
+		}
+		/* Get (read) several nodes by their global ids
+		   http://localhost:8080/document?query={nodes(ids:["RG9jdW1lbnQ6MQ=="]){id}}
+		*/
+		fields["nodes"] = &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(node)),
+			Description: "Get nodes by global ids, null for missing ones",
+			Args: graphql.FieldConfigArgument{
+				"ids": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				ids, _ := params.Args["ids"].([]interface{})
+				nodes := []interface{}{}
+				for _, value := range ids {
+					id, _ := value.(string)
+					node, err := resolveNode(params.Context, id)
+					if err != nil {
+						return nil, err
+					}
+					nodes = append(nodes, node)
+				}
+				return nodes, nil
+			},
+		}
+	}
+
+	return graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: version.selectFields("Query", fields),
+		},
+	)
+}
+
+// missingDocument applies the not-found policy to err, returned by the store
+// for the document with the given id: a missing document is null in queries
+// and an error in mutations
+func missingDocument(p graphql.ResolveParams, id int64, err error) (interface{}, error) {
+	if !errors.Is(err, errNotFound) {
+		return nil, err
+	}
+	if operation, ok := p.Info.Operation.(*ast.OperationDefinition); ok && operation.Operation == ast.OperationTypeMutation {
+		return nil, &documentNotFoundError{id: id}
+	}
+	return nil, nil
+}
+
+// stringList converts a list argument to strings
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	list := []string{}
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// mutationPayload is the result of a mutation: the affected document and the
+// client mutation id sent with it, as Relay expects
+type mutationPayload struct {
+	ClientMutationID interface{} `json:"clientMutationId"`
+	Document         interface{} `json:"document"`
+	Deleted          bool        `json:"deleted"`
+	Previous         interface{} `json:"previous"`
+}
+
+// countPayload is the result of the mutations changing several documents,
+// like renameTag
+type countPayload struct {
+	ClientMutationID interface{} `json:"clientMutationId"`
+	Count            int         `json:"count"`
+}
+
+// clientMutationIDArg is the optional id clients send to match a mutation
+// with its payload
+var clientMutationIDArg = &graphql.ArgumentConfig{
+	Type:        graphql.String,
+	Description: "Id echoed back in the payload",
+}
+
+// newPayloadType returns the payload type of a mutation, with the fields
+// common to all mutations and the extra ones specific to it
+func newPayloadType(name string, documentType *graphql.Object, extra graphql.Fields) *graphql.Object {
+	fields := graphql.Fields{
+		"clientMutationId": &graphql.Field{
+			Type: graphql.String,
+		},
+		"document": &graphql.Field{
+			Type: documentType,
+		},
+	}
+	for fieldName, field := range extra {
+		fields[fieldName] = field
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:   name,
+		Fields: objectFields(fields),
+	})
+}
+
+// withPayload wraps the document returned by resolve in a mutation payload
+func withPayload(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		document, err := resolve(params)
+		if err != nil {
+			return nil, err
+		}
+		return mutationPayload{
+			ClientMutationID: params.Args["clientMutationId"],
+			Document:         document,
+		}, nil
+	}
+}
+
+// createDocument checks and stores a new document given by a client, as
+// create does. The file type is checked against extension, or if empty, the
+// extension of the name.
+func createDocument(ctx context.Context, document Document, extension string) (Document, error) {
+	document.Tags = uniqueTags(document.Tags)
+	trimDocument(&document)
+	if err := checkExtension(document.Name, extension); err != nil {
+		return Document{}, err
+	}
+	if err := checkTags(document.Tags); err != nil {
+		return Document{}, err
+	}
+	if document.ContentType == "" {
+		data, _ := decodeFile(document.File)
+		document.ContentType = detectContentType(data)
+	}
+	if err := validateDocument(ctx, document); err != nil {
+		return Document{}, err
+	}
+	return store.Create(ctx, document)
+}
+
+// updateDocument changes document by update and stores it, checked as
+// update does
+func updateDocument(ctx context.Context, document Document, update documentUpdate, extension string) (Document, error) {
+	update.apply(&document)
+	trimDocument(&document)
+	if update.Tags != nil {
+		if err := checkTags(document.Tags); err != nil {
+			return Document{}, err
+		}
+	}
+	if err := checkExtension(document.Name, extension); err != nil {
+		return Document{}, err
+	}
+	if err := validateDocument(ctx, document); err != nil {
+		return Document{}, err
+	}
+	return store.Update(ctx, document)
+}
+
+func newMutationType(version schemaVersion, documentType *graphql.Object) *graphql.Object {
+	// name is required unless auto-name mode generates one
+	var nameType graphql.Input = graphql.NewNonNull(graphql.String)
+	if *autoName {
+		nameType = graphql.String
+	}
+
+	fields := graphql.Fields{
+		/* Create new document item
+		curl -H 'Content-Type: application/graphql' -d 'mutation{create(name:"Test File",file:"JVBERi0xLjQK",tags:["report"],clientMutationId:"1"){clientMutationId,document{id,name,file,tags}}}' http://localhost:8080/document
+		*/
+		"create": &graphql.Field{
+			Type:        newPayloadType("CreateDocumentPayload", documentType, nil),
+			Description: "Create new document",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"name": &graphql.ArgumentConfig{
+					Type: nameType,
+				},
+				"file": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"contentType": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Media type of the file, detected from its content if not given",
+				},
+				"tags": &graphql.ArgumentConfig{
+					Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+				},
+				"extension": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "File type checked against the allowed extensions, instead of the extension of the name",
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				name, ok := params.Args["name"].(string)
+				if !ok {
+					name = fmt.Sprintf("Untitled %d", untitledCount.Add(1))
+				}
+				extension, _ := params.Args["extension"].(string)
+				document := Document{
+					Name: name,
+				}
+				// file is optional
+				document.File, _ = params.Args["file"].(string)
+				document.ContentType, _ = params.Args["contentType"].(string)
+				document.Tags = stringList(params.Args["tags"])
+				return createDocument(params.Context, document, extension)
+			}),
+		},
+		/* Update document by id
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{update(id:1,name:"test name"file:"JVBERi0xLjQK"){document{id,name,file}}}' http://localhost:8080/document
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{update(id:1,input:{file:null,tags:["report"]}){document{id,file,tags}}}' http://localhost:8080/document
+		*/
+		"update": &graphql.Field{
+			Type: newPayloadType("UpdateDocumentPayload", documentType, graphql.Fields{
+				"previous": &graphql.Field{
+					Type:        documentType,
+					Description: "The document before the update, if returnPrevious is set",
+				},
+			}),
+			Description: "Update document by id",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"file": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"contentType": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Media type of the file, detected from its content if not given",
+				},
+				"tags": &graphql.ArgumentConfig{
+					Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+				},
+				"extension": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "File type checked against the allowed extensions, instead of the extension of the name",
+				},
+				"input": &graphql.ArgumentConfig{
+					Type:        updateInputType,
+					Description: "Fields to change, which unlike the other arguments can be set to null to clear them",
+				},
+				"returnPrevious": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+					Description:  "Return the document before the update in previous, e.g. to undo it",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				extension, _ := params.Args["extension"].(string)
+				update, err := newDocumentUpdate(params.Args, nullInputFields(params))
+				if err != nil {
+					return nil, err
+				}
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				updated, err := updateDocument(params.Context, document, update, extension)
+				if err != nil {
+					return nil, err
+				}
+				payload := mutationPayload{ClientMutationID: params.Args["clientMutationId"], Document: updated}
+				if returnPrevious, _ := params.Args["returnPrevious"].(bool); returnPrevious {
+					payload.Previous = document
+				}
+				return payload, nil
+			},
+		},
+		/* Change several fields of a document at once, given as a JSON object
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{patch(id:1,fields:{name:"Report",tags:["report","2021"]}){document{id,name,tags}}}' http://localhost:8080/document
+		*/
+		"patch": &graphql.Field{
+			Type:        newPayloadType("PatchDocumentPayload", documentType, nil),
+			Description: "Change the fields of a document given as a JSON object of field names to values, all at once",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"fields": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(jsonType),
+					Description: "Values of name, file, contentType and tags; null clears a field",
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				fields, ok := params.Args["fields"].(map[string]interface{})
+				if !ok {
+					return nil, errors.New("fields must be a JSON object")
+				}
+				update, err := updateFromFields(fields)
+				if err != nil {
+					return nil, err
+				}
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				return updateDocument(params.Context, document, update, "")
+			}),
+		},
+		/* Apply a unified diff to the text of a document's file
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{patchFile(id:1,patch:"@@ -1 +1 @@\n-old\n+new\n"){document{id,file}}}' http://localhost:8080/document
+		*/
+		"patchFile": &graphql.Field{
+			Type:        newPayloadType("PatchFileDocumentPayload", documentType, nil),
+			Description: "Apply a unified diff to the text of the file of a document",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"patch": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				patch, _ := params.Args["patch"].(string)
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				if document, err = withFile(document); err != nil {
+					return nil, err
+				}
+				data, encoded := decodeFile(document.File)
+				patched, err := applyPatch(string(data), patch)
+				if err != nil {
+					return nil, err
+				}
+				document.setFile(encodeFile([]byte(patched), encoded))
+				return store.Update(params.Context, document)
+			}),
+		},
+		/* Append content to the file of a document
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{appendToFile(id:1,content:"bW9yZQ=="){document{id,file,fileSize}}}' http://localhost:8080/document
+		*/
+		"appendToFile": &graphql.Field{
+			Type:        newPayloadType("AppendToFileDocumentPayload", documentType, nil),
+			Description: "Append content, base64 encoded like files, to the file of a document",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"content": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				content, _ := params.Args["content"].(string)
+				appended, err := base64.StdEncoding.Strict().DecodeString(content)
+				if err != nil {
+					return nil, errors.New("content must be base64 encoded")
+				}
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				if document, err = withFile(document); err != nil {
+					return nil, err
+				}
+				data, encoded := decodeFile(document.File)
+				if document.File == "" {
+					encoded = true
+				}
+				data = append(data, appended...)
+				document.setFile(encodeFile(data, encoded))
+				if document.ContentType == "" {
+					document.ContentType = detectContentType(data)
+				}
+				return store.Update(params.Context, document)
+			}),
+		},
+		/* Remove the file of a document, keeping the document
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{clearFile(id:1){document{id,name,file}}}' http://localhost:8080/document
+		*/
+		"clearFile": &graphql.Field{
+			Type:        newPayloadType("ClearFileDocumentPayload", documentType, nil),
+			Description: "Remove the file of a document and its content type, keeping the document",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				document, err := store.Get(params.Context, id)
+				if err != nil {
+					return missingDocument(params, id, err)
+				}
+				document.setFile("")
+				document.ContentType = ""
+				return store.Update(params.Context, document)
+			}),
+		},
+		/* Merge a document into another one, deleting it
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{merge(intoId:1,fromId:2,conflict:KEEP_INTO){document{id,name,tags}}}' http://localhost:8080/document
+		*/
+		"merge": &graphql.Field{
+			Type:        newPayloadType("MergeDocumentsPayload", documentType, nil),
+			Description: "Merge a document into another one, which keeps its id, and delete it",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"intoId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"fromId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+				"conflict": &graphql.ArgumentConfig{
+					Type: graphql.NewEnum(graphql.EnumConfig{
+						Name:        "MergeConflict",
+						Description: "Which document wins for fields both documents have",
+						Values: graphql.EnumValueConfigMap{
+							"KEEP_INTO": &graphql.EnumValueConfig{Value: "keepInto"},
+							"KEEP_FROM": &graphql.EnumValueConfig{Value: "keepFrom"},
+						},
+					}),
+					DefaultValue: "keepInto",
+				},
+			},
+			Resolve: withPayload(func(params graphql.ResolveParams) (interface{}, error) {
+				intoID, err := documentID(params.Args["intoId"])
+				if err != nil {
+					return nil, err
+				}
+				fromID, err := documentID(params.Args["fromId"])
+				if err != nil {
+					return nil, err
+				}
+				if intoID == fromID {
+					return nil, errors.New("cannot merge a document into itself")
+				}
+				into, err := store.Get(params.Context, intoID)
+				if err != nil {
+					return missingDocument(params, intoID, err)
+				}
+				from, err := store.Get(params.Context, fromID)
+				if err != nil {
+					return missingDocument(params, fromID, err)
+				}
+				merged, err := mergeDocuments(into, from, params.Args["conflict"] == "keepFrom")
+				if err != nil {
+					return nil, err
+				}
+				if err := checkTags(merged.Tags); err != nil {
+					return nil, err
+				}
+				if err := checkExtension(merged.Name, ""); err != nil {
+					return nil, err
+				}
+				if err := validateDocument(params.Context, merged); err != nil {
+					return nil, err
+				}
+				// with unique names, into can only take the name of from once
+				// from is deleted, so it is renamed last
+				name := merged.Name
+				if *uniqueNames && strings.EqualFold(name, from.Name) {
+					merged.Name = into.Name
+				}
+				// update into before deleting from, so that from isn't lost if
+				// the update fails
+				updated, err := store.Update(params.Context, merged)
+				if err != nil {
+					return nil, err
+				}
+				deleted, err := store.Delete(params.Context, fromID)
+				if err != nil {
+					return nil, err
+				}
+				publishEvent(changeEvent{Type: "delete", Document: deleted, Time: time.Now()})
+				if updated.Name != name {
+					updated.Name = name
+					return store.Update(params.Context, updated)
+				}
+				return updated, nil
+			}),
+		},
+		/* Give several documents to another owner
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{reassignOwner(ids:[1,2],ownerId:7){count}}' http://localhost:8080/document
+		*/
+		"reassignOwner": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "ReassignOwnerPayload",
+				Fields: objectFields(graphql.Fields{
+					"clientMutationId": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type:        graphql.NewNonNull(graphql.Int),
+						Description: "Number of documents updated",
+					},
+				}),
+			}),
+			Description: "Give several documents to another owner",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"ids": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(documentIDType()))),
+				},
+				"ownerId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				values, _ := params.Args["ids"].([]interface{})
+				ids, err := documentIDs(values)
+				if err != nil {
+					return nil, err
+				}
+				ownerID := int64(params.Args["ownerId"].(int))
+				if err := checkOwner(params.Context, ownerID); err != nil {
+					return nil, err
+				}
+				// get them all first, so a missing one fails before any update
+				documents := []Document{}
+				for _, id := range ids {
+					document, err := store.Get(params.Context, id)
+					if err != nil {
+						return missingDocument(params, id, err)
+					}
+					documents = append(documents, document)
+				}
+				payload := countPayload{ClientMutationID: params.Args["clientMutationId"]}
+				for _, document := range documents {
+					if document.OwnerID == ownerID {
+						continue
+					}
+					document.OwnerID = ownerID
+					updated, err := store.Update(params.Context, document)
+					if err != nil {
+						return nil, err
+					}
+					publishEvent(changeEvent{Type: "reassignOwner", Document: updated, Time: time.Now()})
+					payload.Count++
+				}
+				return payload, nil
+			},
+		},
+		/* Rename a tag on every document having it
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{renameTag(from:"reprot",to:"report"){count}}' http://localhost:8080/document
+		*/
+		"renameTag": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "RenameTagPayload",
+				Fields: objectFields(graphql.Fields{
+					"clientMutationId": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type:        graphql.NewNonNull(graphql.Int),
+						Description: "Number of documents renamed",
+					},
+				}),
+			}),
+			Description: "Rename a tag on every document having it, merging it with the new tag where a document has both",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"from": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"to": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				from, _ := params.Args["from"].(string)
+				to, _ := params.Args["to"].(string)
+				if err := checkTag(to); err != nil {
+					return nil, err
+				}
+				payload := countPayload{ClientMutationID: params.Args["clientMutationId"]}
+				if from == to {
+					return payload, nil
+				}
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				for _, document := range documents {
+					tags, found := renameTag(document.Tags, from, to)
+					if !found {
+						continue
+					}
+					document.Tags = tags
+					updated, err := store.Update(params.Context, document)
+					if err != nil {
+						return nil, err
+					}
+					publishEvent(changeEvent{Type: "renameTag", Document: updated, Time: time.Now()})
+					payload.Count++
+				}
+				return payload, nil
+			},
+		},
+		/* Add a tag to every document whose name contains a string
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{tagMatching(nameContains:"invoice",tag:"billing"){count}}' http://localhost:8080/document
+		*/
+		"tagMatching": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "TagMatchingPayload",
+				Fields: objectFields(graphql.Fields{
+					"clientMutationId": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type:        graphql.NewNonNull(graphql.Int),
+						Description: "Number of documents tagged",
+					},
+				}),
+			}),
+			Description: "Add a tag to every document whose name contains a string, ignoring case",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"nameContains": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"tag": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				tag, _ := params.Args["tag"].(string)
+				if err := checkTag(tag); err != nil {
+					return nil, err
+				}
+				// an empty string would match every document
+				if nameContains, _ := params.Args["nameContains"].(string); nameContains == "" {
+					return nil, errors.New("nameContains can't be empty")
+				}
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				var tagging []Document
+				for _, document := range newDocumentFilter(params.Args).apply(documents) {
+					tags := uniqueTags(append(document.Tags, tag))
+					if len(tags) == len(document.Tags) {
+						continue // already tagged
+					}
+					document.Tags = tags
+					// check all documents first so that none is tagged if one can't be
+					if err := checkTags(document.Tags); err != nil {
+						return nil, fmt.Errorf("document %s: %w", idString(document.ID), err)
+					}
+					tagging = append(tagging, document)
+				}
+				payload := countPayload{ClientMutationID: params.Args["clientMutationId"]}
+				for _, document := range tagging {
+					updated, err := store.Update(params.Context, document)
+					if err != nil {
+						return nil, err
+					}
+					publishEvent(changeEvent{Type: "tagMatching", Document: updated, Time: time.Now()})
+					payload.Count++
+				}
+				return payload, nil
+			},
+		},
+		/* Set the content type of the documents without one from the extension of their name
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{inferContentTypes{count}}' http://localhost:8080/document
+		*/
+		"inferContentTypes": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "InferContentTypesPayload",
+				Fields: objectFields(graphql.Fields{
+					"clientMutationId": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type:        graphql.NewNonNull(graphql.Int),
+						Description: "Number of documents given a content type",
+					},
+				}),
+			}),
+			Description: "Set the content type of the documents without one from the extension of their name, skipping unknown extensions",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				documents, err := store.List(params.Context)
+				if err != nil {
+					return nil, err
+				}
+				payload := countPayload{ClientMutationID: params.Args["clientMutationId"]}
+				for _, document := range documents {
+					if document.ContentType != "" {
+						continue
+					}
+					if document.ContentType = inferContentType(document.Name); document.ContentType == "" {
+						continue // no or unknown extension
+					}
+					updated, err := store.Update(params.Context, document)
+					if err != nil {
+						return nil, err
+					}
+					publishEvent(changeEvent{Type: "inferContentTypes", Document: updated, Time: time.Now()})
+					payload.Count++
+				}
+				return payload, nil
+			},
+		},
+		/* Delete document by id
+		   curl -H 'Content-Type: application/graphql' -d 'mutation{delete(id:1){deleted,document{id,name,file}}}' http://localhost:8080/document
+		*/
+		"delete": &graphql.Field{
+			Type: newPayloadType("DeleteDocumentPayload", documentType, graphql.Fields{
+				"deleted": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Boolean),
+					Description: "Whether the document was removed; false if there was no document with this id, e.g. when retrying a delete",
+				},
+			}),
+			Description: "Delete document by id",
+			Args: graphql.FieldConfigArgument{
+				"clientMutationId": clientMutationIDArg,
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(documentIDType()),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				id, err := documentID(params.Args["id"])
+				if err != nil {
+					return nil, err
+				}
+				payload := mutationPayload{ClientMutationID: params.Args["clientMutationId"]}
+				document, err := store.Delete(params.Context, id)
+				if errors.Is(err, errNotFound) {
+					// deleting again is not an error, so deletes can be retried
+					return payload, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				payload.Document = document
+				payload.Deleted = true
+				return payload, nil
+			},
+		},
+	}
+	fields = version.selectFields("Mutation", fields)
+	for name, field := range fields {
+		field.Resolve = withChangeEvents(name, withMutationHooks(name, field.Resolve))
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Mutation",
+		Fields: fields,
+	})
+}
+
+// newSchema returns the schema of a version of the API
+func newSchema(version schemaVersion) (graphql.Schema, error) {
+	documentType := newDocumentType(version)
+	config := graphql.SchemaConfig{
+		Query:      newQueryType(version, documentType),
+		Directives: append([]*graphql.Directive{streamDirective}, graphql.SpecifiedDirectives...),
+	}
+	// a read-only schema has no mutations
+	if !*readOnly {
+		config.Mutation = newMutationType(version, documentType)
+	}
+	return buildSchema(config, !*readOnly)
+}
+
+// buildSchema checks the root types of config before building the schema,
+// so a missing type fails at startup with a clear error rather than with a
+// nil dereference once a request reaches it. Only schemas without
+// mutations can lack the Mutation type.
+func buildSchema(config graphql.SchemaConfig, mutations bool) (graphql.Schema, error) {
+	if config.Query == nil {
+		return graphql.Schema{}, errors.New("missing the Query type")
+	}
+	if mutations && config.Mutation == nil {
+		return graphql.Schema{}, errors.New("missing the Mutation type, run with -read-only to serve queries only")
+	}
+	return graphql.NewSchema(config)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestCreateRequiresANameWithoutAutoName(t *testing.T) {
+	newTestStore(t)
+	result := execute(context.Background(), newTestSchema(t), `mutation{create(file:"SGVsbG8="){document{id}}}`, nil)
+	if !result.HasErrors() {
+		t.Fatal("create without a name succeeded")
+	}
+}
+
+func TestAutoName(t *testing.T) {
+	setVar(t, autoName, true)
+	newTestStore(t)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{create(name:"Report"){document{name}}}`, nil)
+	if name := lookup(data, "create", "document", "name"); name != "Report" {
+		t.Errorf("name of a named document = %v, want Report", name)
+	}
+
+	// concurrent creates must not share a generated name
+	const creates = 20
+	names := make(chan string, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := execute(context.Background(), schema, `mutation{create{document{name}}}`, nil)
+			if result.HasErrors() {
+				t.Error(result.Errors)
+				return
+			}
+			name, _ := lookup(result.Data, "create", "document", "name").(string)
+			names <- name
+		}()
+	}
+	wg.Wait()
+	close(names)
+	seen := map[string]bool{}
+	for name := range names {
+		if !strings.HasPrefix(name, "Untitled ") {
+			t.Errorf("generated name %q, want Untitled <n>", name)
+		}
+		if seen[name] {
+			t.Errorf("name %q generated twice", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestAppendToFile(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{appendToFile(id:1,content:"bW9yZQ=="){document{file,fileSize}}}`, nil)
+	// "Hello, World!" then "more"
+	if file := lookup(data, "appendToFile", "document", "file"); file != "SGVsbG8sIFdvcmxkIW1vcmU=" {
+		t.Errorf("file = %v, want Hello, World!more", file)
+	}
+	if size := lookup(data, "appendToFile", "document", "fileSize"); size != 17.0 {
+		t.Errorf("fileSize = %v, want 17", size)
+	}
+
+	result := execute(context.Background(), schema, `mutation{appendToFile(id:1,content:"more!"){document{file}}}`, nil)
+	if !result.HasErrors() {
+		t.Error("appending content that isn't base64 succeeded")
+	}
+	data = mustExecute(t, schema, `{document(id:1){file}}`, nil)
+	if file := lookup(data, "document", "file"); file != "SGVsbG8sIFdvcmxkIW1vcmU=" {
+		t.Errorf("file after a failed append = %v", file)
+	}
+}
+
+func TestDocumentsByIds(t *testing.T) {
+	newTestStore(t, testDocuments(3)...)
+	schema := newTestSchema(t)
+	for _, test := range []struct {
+		query string
+		want  []interface{}
+	}{
+		{"{documentsByIds(ids:[3,1,2]){id}}", []interface{}{3.0, 1.0, 2.0}},
+		{"{documentsByIds(ids:[3,99,1]){id}}", []interface{}{3.0, nil, 1.0}},
+		{"{documentsByIds(ids:[3,99,1],omitMissing:true){id}}", []interface{}{3.0, 1.0}},
+		{"{documentsByIds(ids:[]){id}}", []interface{}{}},
+	} {
+		documents, _ := lookup(mustExecute(t, schema, test.query, nil), "documentsByIds").([]interface{})
+		ids := []interface{}{}
+		for _, document := range documents {
+			ids = append(ids, lookup(document, "id"))
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.want) {
+			t.Errorf("%s = ids %v, want %v", test.query, ids, test.want)
+		}
+	}
+}
+
+func TestNextID(t *testing.T) {
+	newTestStore(t, testDocuments(3)...)
+	schema := newTestSchema(t)
+	for i := 0; i < 2; i++ {
+		next := lookup(mustExecute(t, schema, "{nextId}", nil), "nextId")
+		// previewing the id doesn't take it
+		if again := lookup(mustExecute(t, schema, "{nextId}", nil), "nextId"); again != next {
+			t.Errorf("nextId = %v then %v", next, again)
+		}
+		created := lookup(mustExecute(t, schema, `mutation{create(name:"Report"){document{id}}}`, nil), "create", "document", "id")
+		if created != next {
+			t.Errorf("created document %v, nextId was %v", created, next)
+		}
+	}
+}
+
+func TestClientMutationID(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(clientMutationId:"a",name:"Report"){clientMutationId,document{name}}}`,
+		`mutation{update(clientMutationId:"a",id:1,name:"Report"){clientMutationId,document{name}}}`,
+		`mutation{delete(clientMutationId:"a",id:1){clientMutationId,document{name}}}`,
+	} {
+		data := mustExecute(t, schema, query, nil)
+		for _, payload := range data {
+			if id := lookup(payload, "clientMutationId"); id != "a" {
+				t.Errorf("%s: clientMutationId = %v, want a", query, id)
+			}
+			if name := lookup(payload, "document", "name"); name != "Report" {
+				t.Errorf("%s: document name = %v, want Report", query, name)
+			}
+		}
+	}
+	data := mustExecute(t, schema, `mutation{create(name:"Other"){clientMutationId}}`, nil)
+	if id := lookup(data, "create", "clientMutationId"); id != nil {
+		t.Errorf("clientMutationId = %v without one sent", id)
+	}
+}
+
+func TestDeleteIsRetrySafe(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{delete(id:1){deleted,document{id}}}`, nil)
+	if deleted := lookup(data, "delete", "deleted"); deleted != true {
+		t.Errorf("first delete: deleted = %v, want true", deleted)
+	}
+	if id := lookup(data, "delete", "document", "id"); id != 1.0 {
+		t.Errorf("first delete: document %v, want 1", id)
+	}
+	data = mustExecute(t, schema, `mutation{delete(id:1){deleted,document{id}}}`, nil)
+	if deleted := lookup(data, "delete", "deleted"); deleted != false {
+		t.Errorf("repeated delete: deleted = %v, want false", deleted)
+	}
+	if document := lookup(data, "delete", "document"); document != nil {
+		t.Errorf("repeated delete: document %v, want null", document)
+	}
+}
+
+func TestMissingDocuments(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	// queries get null
+	for _, query := range []string{
+		"{document(id:99999){id}}",
+		"{documentMeta(id:99999){id}}",
+		`{verifyFile(id:99999,sha256:""){matches}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if result.HasErrors() {
+			t.Errorf("%s: %v, want null", query, result.Errors)
+			continue
+		}
+		for field, value := range result.Data.(map[string]interface{}) {
+			if value != nil {
+				t.Errorf("%s: %s = %v, want null", query, field, value)
+			}
+		}
+	}
+	// mutations fail
+	for _, query := range []string{
+		`mutation{update(id:99999,name:"Report"){document{id}}}`,
+		`mutation{patch(id:99999,fields:{name:"Report"}){document{id}}}`,
+		`mutation{clearFile(id:99999){document{id}}}`,
+		`mutation{appendToFile(id:99999,content:"bW9yZQ=="){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() || result.Errors[0].Message != "document 99999 not found" {
+			t.Errorf("%s: errors %v, want document 99999 not found", query, result.Errors)
+		}
+	}
+}
+
+func TestClearFile(t *testing.T) {
+	updatedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newTestStore(t, Document{ID: 1, Name: "Report", File: "SGVsbG8=", ContentType: "text/plain", Tags: []string{"draft"}, UpdatedAt: updatedAt})
+	data := mustExecute(t, newTestSchema(t), `mutation{clearFile(id:1){document{name,file,fileSize,contentType,tags,updatedAt}}}`, nil)
+	document := lookup(data, "clearFile", "document")
+	if lookup(document, "file") != "" || lookup(document, "fileSize") != 0.0 || lookup(document, "contentType") != "" {
+		t.Errorf("document %v, want no file or content type", document)
+	}
+	if lookup(document, "name") != "Report" || fmt.Sprint(lookup(document, "tags")) != "[draft]" {
+		t.Errorf("document %v, want its name and tags kept", document)
+	}
+	if got, _ := time.Parse(time.RFC3339, lookup(document, "updatedAt").(string)); !got.After(updatedAt) {
+		t.Errorf("updatedAt = %v, want it bumped", got)
+	}
+}
+
+func BenchmarkList(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			newTestStore(b, testDocuments(size)...)
+			setVar(b, maxPageSize, size)
+			schema := newTestSchema(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if result := execute(context.Background(), schema, "{list{id,name,fileSize}}", nil); result.HasErrors() {
+					b.Fatal(result.Errors)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDocument(b *testing.B) {
+	newTestStore(b, testDocuments(1000)...)
+	schema := newTestSchema(b)
+	variables := map[string]interface{}{"id": 500}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if result := execute(context.Background(), schema, "query($id:Int!){document(id:$id){id,name,file}}", variables); result.HasErrors() {
+			b.Fatal(result.Errors)
+		}
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	newTestStore(b)
+	schema := newTestSchema(b)
+	variables := map[string]interface{}{"file": "SGVsbG8sIFdvcmxkIQ=="}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		variables["name"] = fmt.Sprintf("Document %d", i)
+		if result := execute(context.Background(), schema, "mutation($name:String!,$file:String){create(name:$name,file:$file){document{id}}}", variables); result.HasErrors() {
+			b.Fatal(result.Errors)
+		}
+	}
+}
+
+func TestBuildSchemaChecksRootTypes(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"ok": &graphql.Field{Type: graphql.Boolean},
+	}})
+	for _, test := range []struct {
+		name      string
+		config    graphql.SchemaConfig
+		mutations bool
+		err       string
+	}{
+		{"no Query", graphql.SchemaConfig{}, false, "missing the Query type"},
+		{"no Mutation", graphql.SchemaConfig{Query: query}, true, "missing the Mutation type"},
+		{"read-only", graphql.SchemaConfig{Query: query}, false, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := buildSchema(test.config, test.mutations)
+			if test.err == "" && err != nil {
+				t.Errorf("error %v, want none", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedReadStore collapses concurrent Gets of the same document into one
+// call to its store, whose result all of them get. This keeps a burst of
+// requests for a popular document from piling up on a slow store.
+type sharedReadStore struct {
+	Store
+	gets *singleflight.Group
+}
+
+func newSharedReadStore(s Store) sharedReadStore {
+	return sharedReadStore{Store: s, gets: &singleflight.Group{}}
+}
+
+func (s sharedReadStore) Get(ctx context.Context, id int64) (Document, error) {
+	value, err, shared := s.gets.Do(strconv.FormatInt(id, 10), func() (interface{}, error) {
+		return s.Store.Get(ctx, id)
+	})
+	// the call ran with the context of the first caller, which may have
+	// been canceled while ours wasn't
+	if shared && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return s.Store.Get(ctx, id)
+	}
+	document, _ := value.(Document)
+	if shared {
+		// callers may change the tags of their document
+		document.Tags = append([]string(nil), document.Tags...)
+	}
+	return document, err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// blockingStore holds gets until release is closed, counting them
+type blockingStore struct {
+	Store
+	gets    atomic.Int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, id int64) (Document, error) {
+	if s.gets.Add(1) == 1 {
+		close(s.started)
+	}
+	<-s.release
+	return s.Store.Get(ctx, id)
+}
+
+// enteringStore tells of each get on entered before making it
+type enteringStore struct {
+	Store
+	entered chan struct{}
+}
+
+func (s enteringStore) Get(ctx context.Context, id int64) (Document, error) {
+	s.entered <- struct{}{}
+	return s.Store.Get(ctx, id)
+}
+
+func TestSharedReads(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	blocking := &blockingStore{Store: store, started: make(chan struct{}), release: make(chan struct{})}
+	const readers = 10
+	entering := enteringStore{Store: newSharedReadStore(blocking), entered: make(chan struct{}, readers)}
+	setVar(t, &store, Store(entering))
+	schema := newTestSchema(t)
+
+	var wg sync.WaitGroup
+	names := make(chan interface{}, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := execute(context.Background(), schema, "{document(id:1){name}}", nil)
+			if result.HasErrors() {
+				t.Error(result.Errors)
+				return
+			}
+			names <- lookup(result.Data, "document", "name")
+		}()
+	}
+	// release the first get once all the readers have asked for the document
+	<-blocking.started
+	for i := 0; i < readers; i++ {
+		<-entering.entered
+	}
+	close(blocking.release)
+	wg.Wait()
+	close(names)
+
+	if got := blocking.gets.Load(); got != 1 {
+		t.Errorf("%d gets, want 1", got)
+	}
+	for name := range names {
+		if name != "Document 1" {
+			t.Errorf("name %v, want Document 1", name)
+		}
+	}
+}
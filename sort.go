@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// sortableFields are the fields lists can be sorted by
+var sortableFields = []string{"ID", "NAME"}
+
+// checkSortField returns an error naming the sortable fields if documents
+// can't be sorted by field. Fields are case insensitive.
+func checkSortField(field string) error {
+	for _, sortable := range sortableFields {
+		if strings.EqualFold(field, sortable) {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot sort by %q, sortable fields are %s", field, strings.Join(sortableFields, ", "))
+}
+
+// sortOrder is the field and direction a list is sorted by
+type sortOrder struct {
+	field      string
+	descending bool
+}
+
+// defaultSortOrder is the order of lists whose query doesn't give one, set
+// from -default-sort at startup
+var defaultSortOrder = sortOrder{field: "ID"}
+
+// parseSortOrder reads a sort order written as field:asc or field:desc, e.g.
+// name:desc. The direction can be left out for ascending.
+func parseSortOrder(value string) (sortOrder, error) {
+	field, direction, _ := strings.Cut(value, ":")
+	if err := checkSortField(field); err != nil {
+		return sortOrder{}, err
+	}
+	order := sortOrder{field: strings.ToUpper(field)}
+	switch strings.ToLower(direction) {
+	case "", "asc":
+	case "desc":
+		order.descending = true
+	default:
+		return sortOrder{}, fmt.Errorf("invalid direction %q, expected asc or desc", direction)
+	}
+	return order, nil
+}
+
+// String returns the order as -default-sort takes it, e.g. ID:asc
+func (o sortOrder) String() string {
+	if o.descending {
+		return o.field + ":desc"
+	}
+	return o.field + ":asc"
+}
+
+// reverseDocuments reverses the order of documents in place
+func reverseDocuments(documents []Document) {
+	for i, j := 0, len(documents)-1; i < j; i, j = i+1, j-1 {
+		documents[i], documents[j] = documents[j], documents[i]
+	}
+}
+
+// sortEntry is a document as kept by a sortIndex
+type sortEntry struct {
+	id   int64
+	name string
+}
+
+// sortIndex keeps the ids of documents in one sort order, updated as
+// documents change so that sorted lists don't sort the whole collection
+type sortIndex struct {
+	entries []sortEntry
+	less    func(a, b sortEntry) bool
+}
+
+// newSortIndexes returns the indexes of the sortable fields, keyed by their
+// lowercased name. Names are compared with the collation rules of
+// -collation-locale, so accented letters sort next to their base letter
+// instead of after "z". Ties are broken by id.
+func newSortIndexes() map[string]*sortIndex {
+	// collators are not safe for concurrent use, the index is only changed
+	// with the store locked
+	collator := collate.New(language.Make(*collationLocale))
+	return map[string]*sortIndex{
+		"id": {less: func(a, b sortEntry) bool {
+			return a.id < b.id
+		}},
+		"name": {less: func(a, b sortEntry) bool {
+			if c := collator.CompareString(a.name, b.name); c != 0 {
+				return c < 0
+			}
+			return a.id < b.id
+		}},
+	}
+}
+
+// add inserts the entry of document
+func (x *sortIndex) add(document Document) {
+	entry := sortEntry{id: document.ID, name: document.Name}
+	i := sort.Search(len(x.entries), func(i int) bool {
+		return x.less(entry, x.entries[i])
+	})
+	x.entries = append(x.entries, sortEntry{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = entry
+}
+
+// remove deletes the entry of the document with the given id
+func (x *sortIndex) remove(id int64) {
+	for i, entry := range x.entries {
+		if entry.id == id {
+			x.entries = append(x.entries[:i], x.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// namesAfter returns the documents, sorted by name with ties broken by id
+// as the name index sorts them, that sort strictly after the document with
+// the given name and id. An id of math.MaxInt64 skips all the documents
+// with that name.
+func namesAfter(documents []Document, name string, id int64) []Document {
+	collator := collate.New(language.Make(*collationLocale))
+	i := sort.Search(len(documents), func(i int) bool {
+		if c := collator.CompareString(documents[i].Name, name); c != 0 {
+			return c > 0
+		}
+		return documents[i].ID > id
+	})
+	return documents[i:]
+}
+
+// sortRecentlyUpdated sorts documents by update time, most recent first.
+// Ties are broken by id, highest first.
+func sortRecentlyUpdated(documents []Document) {
+	sort.SliceStable(documents, func(i, j int) bool {
+		if !documents[i].UpdatedAt.Equal(documents[j].UpdatedAt) {
+			return documents[i].UpdatedAt.After(documents[j].UpdatedAt)
+		}
+		return documents[i].ID > documents[j].ID
+	})
+}
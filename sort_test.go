@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+func TestListByNamePages(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "Budget"},
+		Document{ID: 2, Name: "Report"},
+		Document{ID: 3, Name: "Report"},
+		Document{ID: 4, Name: "Agenda"},
+		Document{ID: 5, Name: "Report"},
+		Document{ID: 6, Name: "Minutes"},
+		Document{ID: 7, Name: "Zoning"},
+	)
+	schema := newTestSchema(t)
+	var ids []interface{}
+	variables := map[string]interface{}{}
+	for page := 0; page < 10; page++ {
+		data := mustExecute(t, schema, "query($name:String,$id:Int){listByName(afterName:$name,afterId:$id,limit:2){id,name}}", variables)
+		documents := data["listByName"].([]interface{})
+		if len(documents) == 0 {
+			break
+		}
+		for _, document := range documents {
+			ids = append(ids, lookup(document, "id"))
+		}
+		last := documents[len(documents)-1]
+		variables = map[string]interface{}{"name": lookup(last, "name"), "id": lookup(last, "id")}
+	}
+	// ties between the three Reports are broken by id
+	want := []interface{}{4.0, 1.0, 6.0, 2.0, 3.0, 5.0, 7.0}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids of the pages = %v, want %v", ids, want)
+	}
+}
+
+func TestListByNameWithoutAfterIdSkipsTheName(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Report"}, Document{ID: 2, Name: "Report"}, Document{ID: 3, Name: "Zoning"})
+	data := mustExecute(t, newTestSchema(t), `{listByName(afterName:"Report"){id}}`, nil)
+	if got, want := data["listByName"], []interface{}{map[string]interface{}{"id": 3.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listByName = %v, want %v", got, want)
+	}
+}
+
+// listIDs returns the ids of the documents of a list query
+func listIDs(tb testing.TB, data map[string]interface{}) []interface{} {
+	tb.Helper()
+	var ids []interface{}
+	for _, document := range data["list"].([]interface{}) {
+		ids = append(ids, lookup(document, "id"))
+	}
+	return ids
+}
+
+func TestDefaultSort(t *testing.T) {
+	order, err := parseSortOrder("name:desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVar(t, &defaultSortOrder, order)
+	newTestStore(t, Document{ID: 1, Name: "Budget"}, Document{ID: 2, Name: "Agenda"}, Document{ID: 3, Name: "Minutes"})
+	schema := newTestSchema(t)
+	for _, test := range []struct {
+		query string
+		want  []interface{}
+	}{
+		{"{list{id}}", []interface{}{3.0, 1.0, 2.0}},
+		// the direction of the flag doesn't apply to another field
+		{`{list(sortBy:"ID"){id}}`, []interface{}{1.0, 2.0, 3.0}},
+		{`{list(sortBy:"NAME"){id}}`, []interface{}{2.0, 1.0, 3.0}},
+		{`{list(sortBy:"ID",descending:true){id}}`, []interface{}{3.0, 2.0, 1.0}},
+		{"{list(descending:false){id}}", []interface{}{2.0, 1.0, 3.0}},
+	} {
+		if got := listIDs(t, mustExecute(t, schema, test.query, nil)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	for value, want := range map[string]sortOrder{
+		"id":        {field: "ID"},
+		"id:asc":    {field: "ID"},
+		"NAME:DESC": {field: "NAME", descending: true},
+	} {
+		if got, err := parseSortOrder(value); err != nil || got != want {
+			t.Errorf("parseSortOrder(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "size:asc", "name:up", ":desc"} {
+		if _, err := parseSortOrder(value); err == nil {
+			t.Errorf("parseSortOrder(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestCollatedNameSort(t *testing.T) {
+	names := []string{"zebra", "Émile", "eagle", "Öland", "apple", "omega"}
+	documents := make([]Document, len(names))
+	for i, name := range names {
+		documents[i] = Document{ID: int64(i + 1), Name: name}
+	}
+	sortedNames := func(locale string) []string {
+		setVar(t, collationLocale, locale)
+		newTestStore(t, documents...)
+		var sorted []string
+		for _, document := range mustExecute(t, newTestSchema(t), `{list(sortBy:"NAME"){name}}`, nil)["list"].([]interface{}) {
+			sorted = append(sorted, lookup(document, "name").(string))
+		}
+		return sorted
+	}
+
+	// bytes put accented capitals after every ASCII letter
+	bytes := append([]string(nil), names...)
+	sort.Strings(bytes)
+	if want := []string{"apple", "eagle", "omega", "zebra", "Émile", "Öland"}; !reflect.DeepEqual(bytes, want) {
+		t.Fatalf("names sorted by bytes = %v, want %v", bytes, want)
+	}
+	if got, want := sortedNames("en"), []string{"apple", "eagle", "Émile", "Öland", "omega", "zebra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names sorted in en = %v, want %v", got, want)
+	}
+	// in Swedish, ö is a letter after z
+	if got, want := sortedNames("sv"), []string{"apple", "eagle", "Émile", "omega", "zebra", "Öland"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names sorted in sv = %v, want %v", got, want)
+	}
+}
+
+func TestSortFieldAllowList(t *testing.T) {
+	newTestStore(t, testDocuments(2)...)
+	schema := newTestSchema(t)
+	result := execute(context.Background(), schema, `{list(sortBy:"size"){id}}`, nil)
+	if !result.HasErrors() || result.Errors[0].Message != `cannot sort by "size", sortable fields are ID, NAME` {
+		t.Errorf("errors %v, want one listing the sortable fields", result.Errors)
+	}
+	// fields are case insensitive
+	if got := listIDs(t, mustExecute(t, schema, `{list(sortBy:"name",descending:true){id}}`, nil)); !reflect.DeepEqual(got, []interface{}{2.0, 1.0}) {
+		t.Errorf("ids sorted by name = %v, want [2 1]", got)
+	}
+}
+
+func TestSortIndexAfterUpdatesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	memory := newTestStore(t, testDocuments(6)...)
+	for id, name := range map[int64]string{2: "Alpha", 4: "Zulu", 5: "Document 1"} {
+		document, err := memory.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		document.Name = name
+		if _, err := memory.Update(ctx, document); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []int64{3, 6} {
+		if _, err := memory.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := memory.Create(ctx, Document{ID: 7, Name: "Beta"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for field, want := range map[string][]int64{
+		"id":   {1, 2, 4, 5, 7},
+		"name": {2, 7, 1, 5, 4},
+	} {
+		documents, err := memory.ListSorted(ctx, field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []int64{}
+		for _, document := range documents {
+			got = append(got, document.ID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sorted by %s: %v, want %v", field, got, want)
+		}
+	}
+}
+
+// BenchmarkListSortedByName compares the name index with sorting the whole
+// list on each call
+func BenchmarkListSortedByName(b *testing.B) {
+	ctx := context.Background()
+	memory := newTestStore(b, testDocuments(1000)...)
+	b.Run("index", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := memory.ListSorted(ctx, "name"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("sort", func(b *testing.B) {
+		collator := collate.New(language.Make(*collationLocale))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			documents, err := memory.List(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sort.SliceStable(documents, func(i, j int) bool {
+				return collator.CompareString(documents[i].Name, documents[j].Name) < 0
+			})
+		}
+	})
+}
+
+func TestListDefaultsToIDOrder(t *testing.T) {
+	// seeded out of order, so store order isn't id order
+	newTestStore(t, Document{ID: 3, Name: "C"}, Document{ID: 1, Name: "A"}, Document{ID: 2, Name: "B"})
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(name:"D"){document{id}}}`,
+		`mutation{delete(id:1){deleted}}`,
+		`mutation{update(id:3,name:"Z"){document{id}}}`,
+		`mutation{create(name:"E"){document{id}}}`,
+		`mutation{delete(id:4){deleted}}`,
+	} {
+		mustExecute(t, schema, query, nil)
+	}
+	if got := idsOf(mustExecute(t, schema, `{list{id}}`, nil)["list"]); got != "[2 3 5]" {
+		t.Errorf("list %s, want [2 3 5]", got)
+	}
+}
+
+func TestRecentlyUpdated(t *testing.T) {
+	documents := testDocuments(4)
+	for i := range documents {
+		documents[i].UpdatedAt = time.Date(2021, 3, 1+i, 0, 0, 0, 0, time.UTC)
+	}
+	newTestStore(t, documents...)
+	schema := newTestSchema(t)
+	if got := idsOf(mustExecute(t, schema, `{recentlyUpdated{id}}`, nil)["recentlyUpdated"]); got != "[4 3 2 1]" {
+		t.Errorf("recently updated %s, want [4 3 2 1]", got)
+	}
+	mustExecute(t, schema, `mutation{update(id:1,name:"First"){document{id}}}`, nil)
+	mustExecute(t, schema, `mutation{update(id:2,name:"Second"){document{id}}}`, nil)
+	if got := idsOf(mustExecute(t, schema, `{recentlyUpdated(limit:3){id}}`, nil)["recentlyUpdated"]); got != "[2 1 4]" {
+		t.Errorf("recently updated %s, want [2 1 4]", got)
+	}
+}
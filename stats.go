@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// documentStats are aggregate statistics over the stored documents
+type documentStats struct {
+	TotalDocuments       int     `json:"totalDocuments"`
+	TotalFileBytes       int     `json:"totalFileBytes"`
+	AverageFileBytes     float64 `json:"averageFileBytes"`
+	DocumentsWithFile    int     `json:"documentsWithFile"`
+	DocumentsWithoutFile int     `json:"documentsWithoutFile"`
+}
+
+// computeStats returns the statistics of documents. File sizes are those of
+// the decoded files, and the average is over the documents having a file.
+func computeStats(documents []Document) (documentStats, error) {
+	stats := documentStats{TotalDocuments: len(documents)}
+	for _, document := range documents {
+		if !document.HasFile() {
+			stats.DocumentsWithoutFile++
+			continue
+		}
+		document, err := withFile(document)
+		if err != nil {
+			return stats, err
+		}
+		stats.DocumentsWithFile++
+		stats.TotalFileBytes += document.FileSize()
+	}
+	if stats.DocumentsWithFile > 0 {
+		stats.AverageFileBytes = float64(stats.TotalFileBytes) / float64(stats.DocumentsWithFile)
+	}
+	return stats, nil
+}
+
+// unknownContentType groups the documents without a content type
+const unknownContentType = "unknown"
+
+// contentTypeCount is the number of documents having a content type, and
+// the total size of their files
+type contentTypeCount struct {
+	ContentType string `json:"contentType"`
+	Count       int    `json:"count"`
+	TotalBytes  int    `json:"totalBytes"`
+}
+
+// countContentTypes groups documents by content type, with the most
+// documents first. Sizes are those of the decoded files.
+func countContentTypes(documents []Document) ([]contentTypeCount, error) {
+	groups := map[string]*contentTypeCount{}
+	for _, document := range documents {
+		contentType := document.ContentType
+		if contentType == "" {
+			contentType = unknownContentType
+		}
+		group, ok := groups[contentType]
+		if !ok {
+			group = &contentTypeCount{ContentType: contentType}
+			groups[contentType] = group
+		}
+		group.Count++
+		if document.HasFile() {
+			document, err := withFile(document)
+			if err != nil {
+				return nil, err
+			}
+			group.TotalBytes += document.FileSize()
+		}
+	}
+	counts := make([]contentTypeCount, 0, len(groups))
+	for _, group := range groups {
+		counts = append(counts, *group)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].ContentType < counts[j].ContentType
+	})
+	return counts, nil
+}
+
+// mutationCounters count the documents created, updated and deleted since
+// the server started
+type mutationCounters struct {
+	creates atomic.Int64
+	updates atomic.Int64
+	deletes atomic.Int64
+}
+
+// counters are updated by publishEvent
+var counters mutationCounters
+
+// count counts the change of event: mutations other than create and delete,
+// like patch or renameTag, update the documents they change
+func (c *mutationCounters) count(event changeEvent) {
+	switch event.Type {
+	case "create":
+		c.creates.Add(1)
+	case "delete":
+		c.deletes.Add(1)
+	default:
+		c.updates.Add(1)
+	}
+}
+
+// serverStats are the counts reported by the serverStats query
+type serverStats struct {
+	Creates int64 `json:"creates"`
+	Updates int64 `json:"updates"`
+	Deletes int64 `json:"deletes"`
+}
+
+// snapshot returns the current counts
+func (c *mutationCounters) snapshot() serverStats {
+	return serverStats{Creates: c.creates.Load(), Updates: c.updates.Load(), Deletes: c.deletes.Load()}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestCountersConcurrentMutations is meant to run with -race
+func TestCountersConcurrentMutations(t *testing.T) {
+	newTestStore(t, testDocuments(10)...)
+	schema := newTestSchema(t)
+	before := counters.snapshot()
+	const workers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := i + 1
+			for _, query := range []string{
+				fmt.Sprintf(`mutation{create(name:"New %d"){document{id}}}`, i),
+				fmt.Sprintf(`mutation{update(id:%d,name:"Renamed %d"){document{id}}}`, id, i),
+				fmt.Sprintf(`mutation{patch(id:%d,fields:{tags:["patched"]}){document{id}}}`, id),
+				fmt.Sprintf(`mutation{delete(id:%d){deleted}}`, id),
+			} {
+				if result := execute(context.Background(), schema, query, nil); result.HasErrors() {
+					t.Errorf("%s: %v", query, result.Errors)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	after := counters.snapshot()
+	got := serverStats{Creates: after.Creates - before.Creates, Updates: after.Updates - before.Updates, Deletes: after.Deletes - before.Deletes}
+	want := serverStats{Creates: workers, Updates: 2 * workers, Deletes: workers}
+	if got != want {
+		t.Errorf("counted %+v, want %+v", got, want)
+	}
+	data := mustExecute(t, schema, "{serverStats{creates,updates,deletes}}", nil)
+	if creates := lookup(data, "serverStats", "creates"); creates != float64(after.Creates) {
+		t.Errorf("serverStats.creates = %v, want %d", creates, after.Creates)
+	}
+}
+
+func TestStatsQuery(t *testing.T) {
+	// two files of 13 bytes and one of 5, "Hello"
+	documents := append(testDocuments(2), Document{ID: 3, Name: "Short", File: "SGVsbG8="}, Document{ID: 4, Name: "Empty"})
+	newTestStore(t, documents...)
+	data := mustExecute(t, newTestSchema(t), `{stats{totalDocuments,totalFileBytes,averageFileBytes,documentsWithFile,documentsWithoutFile}}`, nil)
+	got := fmt.Sprint(lookup(data, "stats"))
+	want := "map[averageFileBytes:10.333333333333334 documentsWithFile:3 documentsWithoutFile:1 totalDocuments:4 totalFileBytes:31]"
+	if got != want {
+		t.Errorf("stats %s, want %s", got, want)
+	}
+}
+
+func TestStatsWithoutFiles(t *testing.T) {
+	stats, err := computeStats([]Document{{ID: 1, Name: "Empty"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (documentStats{TotalDocuments: 1, DocumentsWithoutFile: 1}); stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestByContentType(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "a.txt", File: "SGVsbG8=", ContentType: "text/plain"},
+		Document{ID: 2, Name: "b.txt", File: "SGVsbG8sIFdvcmxkIQ==", ContentType: "text/plain"},
+		Document{ID: 3, Name: "c.pdf", File: "SGVsbG8=", ContentType: "application/pdf"},
+		Document{ID: 4, Name: "d"},
+	)
+	data := mustExecute(t, newTestSchema(t), `{byContentType{contentType,count,totalBytes}}`, nil)
+	got := fmt.Sprint(data["byContentType"])
+	want := "[map[contentType:text/plain count:2 totalBytes:18] map[contentType:application/pdf count:1 totalBytes:5] map[contentType:unknown count:1 totalBytes:0]]"
+	if got != want {
+		t.Errorf("byContentType %s, want %s", got, want)
+	}
+}
+
+func TestDocumentsMissingContentType(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "a.txt", ContentType: "text/plain"},
+		Document{ID: 2, Name: "b"},
+		Document{ID: 3, Name: "c.pdf", ContentType: "application/pdf"},
+		Document{ID: 4, Name: "d"},
+		Document{ID: 5, Name: "e"},
+	)
+	schema := newTestSchema(t)
+	if got := idsOf(mustExecute(t, schema, `{documentsMissingContentType{id}}`, nil)["documentsMissingContentType"]); got != "[2 4 5]" {
+		t.Errorf("documents %s, want [2 4 5]", got)
+	}
+	if got := idsOf(mustExecute(t, schema, `{documentsMissingContentType(limit:1,offset:1){id}}`, nil)["documentsMissingContentType"]); got != "[4]" {
+		t.Errorf("second page %s, want [4]", got)
+	}
+}
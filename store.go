@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNotFound is returned by the store when no document has the given id
+var errNotFound = errors.New("document not found")
+
+// documentNotFoundError reports a missing document to clients. It is an
+// errNotFound.
+type documentNotFoundError struct {
+	id int64
+}
+
+func (e *documentNotFoundError) Error() string {
+	return fmt.Sprintf("document %s not found", idString(e.id))
+}
+
+func (e *documentNotFoundError) Is(target error) bool {
+	return target == errNotFound
+}
+
+// conflictError is returned by the store when unique names are enforced and
+// the name of a document is taken by another one
+type conflictError struct {
+	id int64 // document having the name
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("name already used by document %s", idString(e.id))
+}
+
+// Extensions reports the conflict in the GraphQL error
+func (e *conflictError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "conflict", "id": formatID(e.id)}
+}
+
+// Store persists documents
+type Store interface {
+	// List returns the documents in store order. If ctx is done before all
+	// are fetched, it returns those fetched so far along with the context
+	// error.
+	List(ctx context.Context) ([]Document, error)
+	// ListSorted is like List, with the documents sorted by field, one of
+	// sortableFields
+	ListSorted(ctx context.Context, field string) ([]Document, error)
+	Get(ctx context.Context, id int64) (Document, error)
+	Search(ctx context.Context, term string) ([]Document, error)
+	NextID(ctx context.Context) (int64, error)
+	Create(ctx context.Context, document Document) (Document, error)
+	Update(ctx context.Context, document Document) (Document, error)
+	Delete(ctx context.Context, id int64) (Document, error)
+}
+
+// record is a document as kept by memoryStore. The file content is held
+// decoded in data, and gzipped if the store compresses files.
+type record struct {
+	document Document
+	data     []byte
+	encoded  bool // the file was base64 encoded
+}
+
+// memoryStore keeps the documents in memory, and their files too unless it
+// has a blob store
+type memoryStore struct {
+	mu       sync.RWMutex
+	compress bool
+	// uniqueNames rejects names used by another document, ignoring case
+	uniqueNames bool
+	ids         idStrategy
+	blobs       BlobStore
+	records     []record
+	nextID      int64
+	index       *nameIndex
+	sorted      map[string]*sortIndex // by lowercased field name
+}
+
+func newMemoryStore(compress, uniqueNames bool, ids idStrategy, blobs BlobStore, documents []Document) (*memoryStore, error) {
+	s := &memoryStore{compress: compress, uniqueNames: uniqueNames, ids: ids, blobs: blobs, nextID: 1, index: newNameIndex(), sorted: newSortIndexes()}
+	now := time.Now()
+	for _, document := range documents {
+		if document.CreatedAt.IsZero() {
+			document.CreatedAt = now
+		}
+		if document.UpdatedAt.IsZero() {
+			document.UpdatedAt = document.CreatedAt
+		}
+		if document.Version == 0 {
+			document.Version = 1
+		}
+		r, err := s.newRecord(document)
+		if err != nil {
+			return nil, err
+		}
+		s.records = append(s.records, r)
+		s.index.add(document.ID, document.Name)
+		s.addSorted(document)
+		if document.ID >= s.nextID {
+			s.nextID = document.ID + 1
+		}
+	}
+	if ids != sequentialIDs {
+		s.advanceID()
+	}
+	return s, nil
+}
+
+// newRecord moves the file content of document out of it, into the blob
+// store or compressed if needed
+func (s *memoryStore) newRecord(document Document) (record, error) {
+	// the stored slice is not shared with callers
+	document.Tags = append([]string(nil), document.Tags...)
+	if s.blobs != nil {
+		// documents without a new file keep their blob
+		if document.File != "" {
+			data, encoded := decodeFile(document.File)
+			ref, err := s.blobs.Put(data)
+			if err != nil {
+				return record{}, err
+			}
+			document.BlobRef = ref
+			document.fileEncoded = encoded
+			document.StoredSize = len(data)
+		} else if document.BlobRef == "" {
+			document.StoredSize = 0
+		}
+		document.File = ""
+		return record{document: document}, nil
+	}
+
+	data, encoded := decodeFile(document.File)
+	if s.compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+	}
+	document.File = ""
+	document.StoredSize = len(data)
+	return record{document: document, data: data, encoded: encoded}, nil
+}
+
+// document restores the document held by r. Files in the blob store are
+// left there, to be loaded by withFile when needed.
+func (s *memoryStore) document(r record) (Document, error) {
+	if s.blobs != nil {
+		document := r.document
+		document.Tags = append([]string(nil), r.document.Tags...)
+		return document, nil
+	}
+	data := r.data
+	if s.compress {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return Document{}, err
+		}
+		data, err = io.ReadAll(zr)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+	document := r.document
+	document.File = encodeFile(data, r.encoded)
+	document.Tags = append([]string(nil), r.document.Tags...)
+	return document, nil
+}
+
+// checkName returns a conflictError if unique names are enforced and
+// another document than id has name. s.mu must be held.
+func (s *memoryStore) checkName(id int64, name string) error {
+	if !s.uniqueNames {
+		return nil
+	}
+	for _, r := range s.records {
+		if r.document.ID != id && strings.EqualFold(r.document.Name, name) {
+			return &conflictError{id: r.document.ID}
+		}
+	}
+	return nil
+}
+
+// addSorted adds document to the sort indexes. s.mu must be held.
+func (s *memoryStore) addSorted(document Document) {
+	for _, index := range s.sorted {
+		index.add(document)
+	}
+}
+
+// removeSorted removes the document with the given id from the sort
+// indexes. s.mu must be held.
+func (s *memoryStore) removeSorted(id int64) {
+	for _, index := range s.sorted {
+		index.remove(id)
+	}
+}
+
+// find returns the index of the record with the given id, or -1. s.mu must
+// be held.
+func (s *memoryStore) find(id int64) int {
+	for i, r := range s.records {
+		if r.document.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryStore) List(ctx context.Context) ([]Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	documents := make([]Document, 0, len(s.records))
+	for _, r := range s.records {
+		if err := ctx.Err(); err != nil {
+			return documents, err
+		}
+		document, err := s.document(r)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// ListSorted returns the documents in the order of the sort index of field
+func (s *memoryStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	index, ok := s.sorted[strings.ToLower(field)]
+	if !ok {
+		return nil, checkSortField(field)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	positions := make(map[int64]int, len(s.records))
+	for i, r := range s.records {
+		positions[r.document.ID] = i
+	}
+	documents := make([]Document, 0, len(index.entries))
+	for _, entry := range index.entries {
+		if err := ctx.Err(); err != nil {
+			return documents, err
+		}
+		document, err := s.document(s.records[positions[entry.id]])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id int64) (Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.find(id)
+	if i < 0 {
+		return Document{}, errNotFound
+	}
+	return s.document(s.records[i])
+}
+
+// Create stores a new document and assigns its id
+func (s *memoryStore) Create(ctx context.Context, document Document) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkName(0, document.Name); err != nil {
+		return Document{}, err
+	}
+	document.ID = s.nextID
+	document.Version = 1
+	document.CreatedAt = time.Now()
+	document.UpdatedAt = document.CreatedAt
+	r, err := s.newRecord(document)
+	if err != nil {
+		return Document{}, err
+	}
+	s.records = append(s.records, r)
+	s.index.add(document.ID, document.Name)
+	s.addSorted(document)
+	s.advanceID()
+	return s.document(r)
+}
+
+// Search returns the documents whose name contains words of term, most
+// relevant first
+func (s *memoryStore) Search(ctx context.Context, term string) ([]Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	documents := []Document{}
+	for _, id := range s.index.search(term) {
+		document, err := s.document(s.records[s.find(id)])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// NextID returns the id the next created document will get
+func (s *memoryStore) NextID(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextID, nil
+}
+
+// Update replaces the stored document with the same id
+func (s *memoryStore) Update(ctx context.Context, document Document) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(document.ID)
+	if i < 0 {
+		return Document{}, errNotFound
+	}
+	if err := s.checkName(document.ID, document.Name); err != nil {
+		return Document{}, err
+	}
+	document.Version = s.records[i].document.Version + 1
+	document.CreatedAt = s.records[i].document.CreatedAt
+	document.UpdatedAt = time.Now()
+	r, err := s.newRecord(document)
+	if err != nil {
+		return Document{}, err
+	}
+	s.records[i] = r
+	s.index.add(document.ID, document.Name)
+	s.removeSorted(document.ID)
+	s.addSorted(document)
+	return s.document(s.records[i])
+}
+
+// Delete removes the document with the given id and returns it
+func (s *memoryStore) Delete(ctx context.Context, id int64) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(id)
+	if i < 0 {
+		return Document{}, errNotFound
+	}
+	document, err := s.document(s.records[i])
+	// Remove from document list
+	s.records = append(s.records[:i], s.records[i+1:]...)
+	s.index.remove(id)
+	s.removeSorted(id)
+	return document, err
+}
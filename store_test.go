@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCompressedFiles(t *testing.T) {
+	setVar(t, compress, true)
+	newTestStore(t)
+	schema := newTestSchema(t)
+	file := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("compressible ", 100)))
+	data := mustExecute(t, schema, `mutation($file:String){create(name:"Report",file:$file){document{id}}}`, map[string]interface{}{"file": file})
+	id := lookup(data, "create", "document", "id")
+
+	data = mustExecute(t, schema, `query($id:Int){document(id:$id){file,fileSize,storedSize}}`, map[string]interface{}{"id": id})
+	if got := lookup(data, "document", "file"); got != file {
+		t.Errorf("file = %v, want the created one", got)
+	}
+	fileSize, _ := lookup(data, "document", "fileSize").(float64)
+	storedSize, _ := lookup(data, "document", "storedSize").(float64)
+	if fileSize != 1300 || storedSize <= 0 || storedSize > fileSize {
+		t.Errorf("fileSize %v, storedSize %v, want a compressed size under 1300", fileSize, storedSize)
+	}
+
+	// the list decompresses files too
+	data = mustExecute(t, schema, "{list{file}}", nil)
+	if got := lookup(data, "list").([]interface{})[0]; lookup(got, "file") != file {
+		t.Errorf("listed file = %v, want the created one", lookup(got, "file"))
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	setVar(t, uniqueNames, true)
+	newTestStore(t, Document{ID: 1, Name: "Report"}, Document{ID: 2, Name: "Budget"})
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(name:"report"){document{id}}}`,
+		`mutation{create(name:"REPORT"){document{id}}}`,
+		`mutation{update(id:2,name:"Report"){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() {
+			t.Errorf("%s succeeded", query)
+			continue
+		}
+		err := result.Errors[0]
+		if err.Message != "name already used by document 1" || err.Extensions["code"] != "conflict" || err.Extensions["id"] != int64(1) {
+			t.Errorf("%s: error %q with extensions %v, want a conflict with document 1", query, err.Message, err.Extensions)
+		}
+	}
+	// a document keeps its own name, in any case
+	mustExecute(t, schema, `mutation{update(id:1,name:"REPORT"){document{id}}}`, nil)
+	mustExecute(t, schema, `mutation{create(name:"Agenda"){document{id}}}`, nil)
+
+	setVar(t, uniqueNames, false)
+	newTestStore(t, Document{ID: 1, Name: "Report"})
+	mustExecute(t, newTestSchema(t), `mutation{create(name:"report"){document{id}}}`, nil)
+}
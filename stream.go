@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// streamDirective declares @stream, with which clients ask for the items of
+// the list query to be delivered incrementally
+var streamDirective = graphql.NewDirective(graphql.DirectiveConfig{
+	Name:        "stream",
+	Description: "Deliver the items of the list after the first initialCount ones incrementally, for clients accepting multipart/mixed responses",
+	Locations:   []string{graphql.DirectiveLocationField},
+	Args: graphql.FieldConfigArgument{
+		"initialCount": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: 0,
+		},
+		"label": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+	},
+})
+
+// listStream is the list query streamed by a request
+type listStream struct {
+	field        *ast.Field
+	key          string // response key of the list field
+	initialCount int
+	label        string
+}
+
+// streamedList returns the list query of req marked with @stream, if any
+func streamedList(req graphqlRequest) (listStream, bool) {
+	operation := selectOperation(req.document, req.OperationName)
+	if operation == nil || operation.Operation != ast.OperationTypeQuery || operation.SelectionSet == nil {
+		return listStream{}, false
+	}
+	for _, selection := range operation.SelectionSet.Selections {
+		field, ok := selection.(*ast.Field)
+		if !ok || field.Name.Value != "list" {
+			continue
+		}
+		for _, directive := range field.Directives {
+			if directive.Name.Value != streamDirective.Name {
+				continue
+			}
+			stream := listStream{field: field, key: field.Name.Value}
+			if field.Alias != nil {
+				stream.key = field.Alias.Value
+			}
+			for _, argument := range directive.Arguments {
+				value := argumentValue(argument.Value, req.Variables)
+				switch argument.Name.Value {
+				case "initialCount":
+					stream.initialCount, _ = strconv.Atoi(value)
+				case "label":
+					stream.label = value
+				}
+			}
+			if stream.initialCount < 0 {
+				stream.initialCount = 0
+			}
+			return stream, true
+		}
+	}
+	return listStream{}, false
+}
+
+// argumentValue returns the value of a scalar argument as a string,
+// resolving variables
+func argumentValue(value ast.Value, variables map[string]interface{}) string {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		return value.Value
+	case *ast.StringValue:
+		return value.Value
+	case *ast.Variable:
+		switch variable := variables[value.Name.Value].(type) {
+		case string:
+			return variable
+		case int64: // integers, as decoded by exactNumbers
+			return strconv.FormatInt(variable, 10)
+		case float64: // other JSON numbers
+			return strconv.FormatFloat(variable, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// acceptsMultipart reports whether the client accepts multipart/mixed
+// responses
+func acceptsMultipart(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "multipart/mixed")
+}
+
+// streamBatchSize is the number of items of a streamed list resolved and
+// sent together after the initial ones
+var streamBatchSize = 100
+
+type streamWindowKey struct{}
+
+// streamWindow is the part of a streamed list resolved by one execution of
+// the query: limit items from offset
+type streamWindow struct {
+	key           string // response key of the list field
+	offset, limit int
+}
+
+// withStreamWindow returns a context resolving only the items of window of
+// the streamed list
+func withStreamWindow(ctx context.Context, window streamWindow) context.Context {
+	return context.WithValue(ctx, streamWindowKey{}, window)
+}
+
+// streamItems returns the documents of the list resolved by params that are
+// in the window of its context, or all of them if it isn't the streamed list
+func streamItems(params graphql.ResolveParams, documents []Document) []Document {
+	window, ok := params.Context.Value(streamWindowKey{}).(streamWindow)
+	if !ok || params.Info.Path == nil || params.Info.Path.Prev != nil || params.Info.Path.Key != window.key {
+		return documents
+	}
+	offset := min(window.offset, len(documents))
+	return documents[offset:min(offset+window.limit, len(documents))]
+}
+
+// streamBatchDocument returns document with the operation of req only
+// selecting the streamed list, to resolve its following items
+func streamBatchDocument(req graphqlRequest, stream listStream) *ast.Document {
+	operation := selectOperation(req.document, req.OperationName)
+	batch := *operation
+	batch.SelectionSet = &ast.SelectionSet{Selections: []ast.Selection{stream.field}}
+	document := *req.document
+	document.Definitions = make([]ast.Node, len(req.document.Definitions))
+	for i, definition := range req.document.Definitions {
+		if definition == ast.Node(operation) {
+			definition = &batch
+		}
+		document.Definitions[i] = definition
+	}
+	return &document
+}
+
+// writeStream runs req, whose list is streamed, and writes its result as a
+// multipart/mixed response. The first part has the other fields and the
+// first initialCount items of the list, and each following part the next
+// streamBatchSize items, only resolved once the part before is written.
+// Items are read by offset, so documents created or deleted during the
+// delivery may be skipped or sent twice.
+func writeStream(w http.ResponseWriter, r *http.Request, req graphqlRequest, schema graphql.Schema, stream listStream) {
+	ctx := r.Context()
+	// each window resolves one more item, to tell whether more follow
+	result := runQuery(withStreamWindow(ctx, streamWindow{key: stream.key, limit: stream.initialCount + 1}), req, schema)
+	if id := requestIDFrom(ctx); id != "" {
+		setExtension(result, "requestId", id)
+	}
+	data, _ := result.Data.(map[string]interface{})
+	items, ok := data[stream.key].([]interface{})
+	if !ok {
+		// the list failed, there is nothing to stream
+		writeJSON(w, r, resultStatus(r, result), result)
+		return
+	}
+
+	mw := multipart.NewWriter(w)
+	mw.SetBoundary("-")
+	w.Header().Set("Content-Type", `multipart/mixed; boundary="-"; deferSpec=20220824`)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	writePart := func(payload map[string]interface{}) bool {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=utf-8"}})
+		if err != nil {
+			return false
+		}
+		if err := json.NewEncoder(part).Encode(payload); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	hasNext := len(items) > stream.initialCount
+	if hasNext {
+		data[stream.key] = items[:stream.initialCount]
+	}
+	first := map[string]interface{}{"data": data, "hasNext": hasNext}
+	if len(result.Errors) > 0 {
+		first["errors"] = result.Errors
+	}
+	if len(result.Extensions) > 0 {
+		first["extensions"] = result.Extensions
+	}
+	if !writePart(first) {
+		return
+	}
+	batch := req
+	batch.document = streamBatchDocument(req, stream)
+	batch.validated = true
+	for offset := stream.initialCount; hasNext; offset += streamBatchSize {
+		if ctx.Err() != nil {
+			return
+		}
+		result := runQuery(withStreamWindow(ctx, streamWindow{key: stream.key, offset: offset, limit: streamBatchSize + 1}), batch, schema)
+		data, _ := result.Data.(map[string]interface{})
+		items, ok := data[stream.key].([]interface{})
+		if !ok {
+			items = []interface{}{}
+		}
+		hasNext = len(items) > streamBatchSize
+		if hasNext {
+			items = items[:streamBatchSize]
+		}
+		incremental := map[string]interface{}{
+			"items": items,
+			"path":  []interface{}{stream.key, offset},
+		}
+		if stream.label != "" {
+			incremental["label"] = stream.label
+		}
+		if len(result.Errors) > 0 {
+			incremental["errors"] = result.Errors
+		}
+		if !writePart(map[string]interface{}{"incremental": []interface{}{incremental}, "hasNext": hasNext}) {
+			return
+		}
+	}
+	mw.Close()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamRequest returns a request for query accepting multipart responses
+func streamRequest(query string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(query))
+	r.Header.Set("Content-Type", "application/graphql")
+	r.Header.Set("Accept", "multipart/mixed")
+	return r
+}
+
+// readChunks decodes the parts of a multipart response
+func readChunks(tb testing.TB, w *httptest.ResponseRecorder) []map[string]interface{} {
+	tb.Helper()
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		tb.Fatalf("Content-Type %q, want multipart/mixed", w.Header().Get("Content-Type"))
+	}
+	var chunks []map[string]interface{}
+	parts := multipart.NewReader(w.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			tb.Fatalf("NextPart: %v", err)
+		}
+		var chunk map[string]interface{}
+		if err := json.NewDecoder(part).Decode(&chunk); err != nil {
+			tb.Fatalf("chunk %d: %v", len(chunks), err)
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestStreamedList(t *testing.T) {
+	setVar(t, &streamBatchSize, 1)
+	newTestStore(t, testDocuments(3)...)
+	w := serve(documentHandler(newTestSchema(t)), streamRequest(`{list @stream(initialCount:1,label:"rest"){id}}`))
+
+	// the first chunk has the first item, then each chunk one more
+	chunks := readChunks(t, w)
+	if len(chunks) != 3 {
+		t.Fatalf("%d chunks, want 3: %v", len(chunks), chunks)
+	}
+	if got, _ := lookup(chunks[0], "data", "list").([]interface{}); len(got) != 1 || lookup(got[0], "id") != 1.0 {
+		t.Errorf("first chunk %v, want document 1", chunks[0])
+	}
+	for i, chunk := range chunks[1:] {
+		incremental, _ := chunk["incremental"].([]interface{})
+		if len(incremental) != 1 {
+			t.Fatalf("chunk %d: %v, want one incremental item", i+1, chunk)
+		}
+		items, _ := lookup(incremental[0], "items").([]interface{})
+		if len(items) != 1 || lookup(items[0], "id") != float64(i+2) || lookup(incremental[0], "label") != "rest" {
+			t.Errorf("chunk %d: %v, want document %d labelled rest", i+1, chunk, i+2)
+		}
+		if hasNext := chunk["hasNext"]; hasNext != (i == 0) {
+			t.Errorf("chunk %d: hasNext %v", i+1, hasNext)
+		}
+	}
+}
+
+func TestStreamedListBatches(t *testing.T) {
+	setVar(t, &streamBatchSize, 2)
+	newTestStore(t, testDocuments(5)...)
+	w := serve(documentHandler(newTestSchema(t)), streamRequest(`{list @stream(initialCount:1){id}}`))
+
+	chunks := readChunks(t, w)
+	if len(chunks) != 3 {
+		t.Fatalf("%d chunks, want 3: %v", len(chunks), chunks)
+	}
+	for i, chunk := range chunks[1:] {
+		incremental, _ := chunk["incremental"].([]interface{})
+		if len(incremental) != 1 {
+			t.Fatalf("chunk %d: %v, want one incremental item", i+1, chunk)
+		}
+		offset := 1 + 2*i
+		items, _ := lookup(incremental[0], "items").([]interface{})
+		if len(items) != 2 || lookup(items[0], "id") != float64(offset+1) || lookup(items[1], "id") != float64(offset+2) {
+			t.Errorf("chunk %d: %v, want documents %d and %d", i+1, chunk, offset+1, offset+2)
+		}
+		if path := fmt.Sprint(lookup(incremental[0], "path")); path != fmt.Sprintf("[list %d]", offset) {
+			t.Errorf("chunk %d: path %s, want [list %d]", i+1, path, offset)
+		}
+		if hasNext := chunk["hasNext"]; hasNext != (i == 0) {
+			t.Errorf("chunk %d: hasNext %v", i+1, hasNext)
+		}
+	}
+}
+
+// flushRecorder is a ResponseRecorder calling onFlush when it's first flushed
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	onFlush func()
+}
+
+func (w *flushRecorder) Flush() {
+	if w.onFlush != nil {
+		w.onFlush()
+		w.onFlush = nil
+	}
+	w.ResponseRecorder.Flush()
+}
+
+func TestStreamedListResolvedAfterFirstPart(t *testing.T) {
+	memory := newTestStore(t, testDocuments(2)...)
+	r := streamRequest(`{list @stream(initialCount:1){id}}`)
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w.onFlush = func() {
+		document := testDocuments(1)[0]
+		document.Name = "late"
+		if _, err := memory.Create(r.Context(), document); err != nil {
+			t.Errorf("Create: %v", err)
+		}
+	}
+	documentHandler(newTestSchema(t)).ServeHTTP(w, r)
+
+	// the document created once the first part is sent is in the next one
+	chunks := readChunks(t, w.ResponseRecorder)
+	if len(chunks) != 2 {
+		t.Fatalf("%d chunks, want 2: %v", len(chunks), chunks)
+	}
+	items, _ := lookup(chunks[1], "incremental").([]interface{})
+	if len(items) != 1 || fmt.Sprint(lookup(items[0], "items")) != "[map[id:2] map[id:3]]" {
+		t.Errorf("second chunk %v, want documents 2 and 3", chunks[1])
+	}
+}
+
+func TestStreamedListInitialCountVariable(t *testing.T) {
+	body := `{"query":"query($n:Int){list @stream(initialCount:$n){id}}","variables":{"n":2}}`
+	r := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	req, err := parseRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.parse()
+	stream, ok := streamedList(req)
+	if !ok {
+		t.Fatal("the list isn't streamed")
+	}
+	if stream.initialCount != 2 {
+		t.Errorf("initialCount = %d, want 2", stream.initialCount)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "sort"
+
+// tagCount is the number of documents having a tag
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// renameTag returns tags with from renamed to, and whether from was there.
+// Renaming to a tag already there merges both.
+func renameTag(tags []string, from, to string) ([]string, bool) {
+	renamed := make([]string, len(tags))
+	found := false
+	for i, tag := range tags {
+		if tag == from {
+			tag = to
+			found = true
+		}
+		renamed[i] = tag
+	}
+	return uniqueTags(renamed), found
+}
+
+// uniqueTags returns tags without duplicates, in their first order
+func uniqueTags(tags []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// countTags returns the tags of documents with the number of documents
+// having each, most used first
+func countTags(documents []Document) []tagCount {
+	counts := map[string]int{}
+	for _, document := range documents {
+		for _, tag := range uniqueTags(document.Tags) {
+			counts[tag]++
+		}
+	}
+	tags := make([]tagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, tagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "One", Tags: []string{"report", "2021"}},
+		Document{ID: 2, Name: "Two", Tags: []string{"report", "draft"}},
+		Document{ID: 3, Name: "Three", Tags: []string{"report", "2021"}},
+		Document{ID: 4, Name: "Four"},
+	)
+	data := mustExecute(t, newTestSchema(t), "{tags{tag,count}}", nil)
+	// most used first, then by tag
+	want := "[map[count:3 tag:report] map[count:2 tag:2021] map[count:1 tag:draft]]"
+	if got := fmt.Sprint(data["tags"]); got != want {
+		t.Errorf("tags = %s, want %s", got, want)
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "One", Tags: []string{"draft", "report"}},
+		Document{ID: 2, Name: "Two", Tags: []string{"draft", "final"}},
+		Document{ID: 3, Name: "Three", Tags: []string{"report"}},
+	)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{renameTag(from:"draft",to:"final"){count}}`, nil)
+	if count := lookup(data, "renameTag", "count"); count != 2.0 {
+		t.Errorf("count = %v, want 2", count)
+	}
+	documents := mustExecute(t, schema, "{list{id,tags}}", nil)["list"].([]interface{})
+	// document 2 already had final, which is kept once
+	want := []string{"[final report]", "[final]", "[report]"}
+	for i, document := range documents {
+		if got := fmt.Sprint(lookup(document, "tags")); got != want[i] {
+			t.Errorf("tags of document %v = %s, want %s", lookup(document, "id"), got, want[i])
+		}
+	}
+	if count := lookup(mustExecute(t, schema, `mutation{renameTag(from:"missing",to:"final"){count}}`, nil), "renameTag", "count"); count != 0.0 {
+		t.Errorf("count of renaming a missing tag = %v, want 0", count)
+	}
+}
+
+func TestTagMatching(t *testing.T) {
+	newTestStore(t,
+		Document{ID: 1, Name: "Invoice March"},
+		Document{ID: 2, Name: "Report"},
+		Document{ID: 3, Name: "old INVOICE", Tags: []string{"archive"}},
+		Document{ID: 4, Name: "Invoice April", Tags: []string{"billing"}},
+	)
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{tagMatching(nameContains:"invoice",tag:"billing"){count}}`, nil)
+	// document 4 already has the tag
+	if count := lookup(data, "tagMatching", "count"); count != 2.0 {
+		t.Errorf("count = %v, want 2", count)
+	}
+	documents := mustExecute(t, schema, "{list{id,tags}}", nil)["list"].([]interface{})
+	want := []string{"[billing]", "[]", "[archive billing]", "[billing]"}
+	for i, document := range documents {
+		if got := fmt.Sprint(lookup(document, "tags")); got != want[i] {
+			t.Errorf("tags of document %v = %s, want %s", lookup(document, "id"), got, want[i])
+		}
+	}
+}
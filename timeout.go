@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// deadlineMargin is the time kept before the query deadline to finish the
+// response once a list stops fetching
+const deadlineMargin = 10 * time.Millisecond
+
+type partialKey struct{}
+
+// withPartialFlag returns a context whose resolvers can flag the result as
+// partial
+func withPartialFlag(ctx context.Context) (context.Context, *atomic.Bool) {
+	partial := &atomic.Bool{}
+	return context.WithValue(ctx, partialKey{}, partial), partial
+}
+
+// markPartial flags the result of the query of ctx as partial
+func markPartial(ctx context.Context) {
+	if partial, ok := ctx.Value(partialKey{}).(*atomic.Bool); ok {
+		partial.Store(true)
+	}
+}
+
+// listDocuments lists the stored documents sorted by sortBy, stopping shortly before the deadline of ctx. Documents fetched
+// until then are returned and the result is flagged as partial, rather than
+// failing the whole query.
+func listDocuments(ctx context.Context, sortBy string) ([]Document, error) {
+	list := func(ctx context.Context) ([]Document, error) {
+		return store.ListSorted(ctx, sortBy)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return list(ctx)
+	}
+	listCtx, cancel := context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+	defer cancel()
+	documents, err := list(listCtx)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		markPartial(ctx)
+		return documents, nil
+	}
+	return documents, err
+}
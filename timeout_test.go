@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slowStore fetches the documents of sorted lists one every delay, and
+// returns those fetched when its context is done
+type slowStore struct {
+	Store
+	delay time.Duration
+}
+
+func (s slowStore) ListSorted(ctx context.Context, field string) ([]Document, error) {
+	all, err := s.Store.ListSorted(ctx, field)
+	if err != nil {
+		return nil, err
+	}
+	var documents []Document
+	for _, document := range all {
+		select {
+		case <-ctx.Done():
+			return documents, ctx.Err()
+		case <-time.After(s.delay):
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func TestPartialList(t *testing.T) {
+	memory := newTestStore(t, testDocuments(50)...)
+	setVar(t, &store, Store(slowStore{Store: memory, delay: 10 * time.Millisecond}))
+	setVar(t, queryTimeout, 100*time.Millisecond)
+	schema := newTestSchema(t)
+
+	result := execute(context.Background(), schema, "{list{id}}", nil)
+	if result.HasErrors() {
+		t.Fatalf("errors %v, want partial results", result.Errors)
+	}
+	if result.Extensions["partial"] != true {
+		t.Errorf("extensions %v, want partial", result.Extensions)
+	}
+	documents, _ := jsonValue(t, result.Data).(map[string]interface{})["list"].([]interface{})
+	if len(documents) == 0 || len(documents) >= 50 {
+		t.Errorf("%d documents, want some of the 50", len(documents))
+	}
+
+	// lists finishing in time are not partial
+	setVar(t, queryTimeout, 5*time.Second)
+	setVar(t, &store, Store(slowStore{Store: memory, delay: 0}))
+	result = execute(context.Background(), schema, "{list(limit:100){id}}", nil)
+	if _, partial := result.Extensions["partial"]; partial || result.HasErrors() {
+		t.Errorf("errors %v, extensions %v, want a complete list", result.Errors, result.Extensions)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// updateInputType is the input of update. Unlike the flat arguments of
+// update, its fields can be set to null explicitly, which clears them,
+// while omitted fields are left unchanged.
+var updateInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "UpdateDocumentInput",
+	Description: "Fields to change; omitted fields are left unchanged and null ones cleared",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"file": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"contentType": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Media type of the file, detected from its content if not given",
+		},
+		"tags": &graphql.InputObjectFieldConfig{
+			Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+		},
+	},
+})
+
+// jsonType is a scalar of any JSON value, taken as is from variables and
+// read from literals in the query
+var jsonType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: jsonLiteral,
+})
+
+// jsonLiteral returns the value of a literal as it would be decoded from
+// JSON. Variables inside literals are not supported and read as null.
+func jsonLiteral(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.ObjectValue:
+		object := map[string]interface{}{}
+		for _, field := range value.Fields {
+			object[field.Name.Value] = jsonLiteral(field.Value)
+		}
+		return object
+	case *ast.ListValue:
+		list := make([]interface{}, len(value.Values))
+		for i, v := range value.Values {
+			list[i] = jsonLiteral(v)
+		}
+		return list
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue, *ast.FloatValue:
+		number, _ := strconv.ParseFloat(value.GetValue().(string), 64)
+		return number
+	}
+	return nil
+}
+
+// documentUpdate is the change update makes to a document. Nil fields are
+// left unchanged; fields pointing to an empty value are cleared.
+type documentUpdate struct {
+	Name        *string
+	File        *string
+	ContentType *string
+	Tags        *[]string
+}
+
+// newDocumentUpdate reads the update from the flat arguments of update and
+// from its input argument, whose fields named in nulls were set to null
+func newDocumentUpdate(args map[string]interface{}, nulls []string) (documentUpdate, error) {
+	fields := map[string]interface{}{}
+	for _, field := range updatableFields {
+		// flat arguments can't be null, nil means omitted
+		if value, ok := args[field]; ok && value != nil {
+			fields[field] = value
+		}
+	}
+	input, _ := args["input"].(map[string]interface{})
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	for _, field := range nulls {
+		input[field] = nil
+	}
+	for field, value := range input {
+		if _, ok := fields[field]; ok {
+			return documentUpdate{}, errors.New(field + " is set both as an argument and in input")
+		}
+		fields[field] = value
+	}
+	return updateFromFields(fields)
+}
+
+// requestVariablesKey is the context key of the variables of a request
+type requestVariablesKey struct{}
+
+// withRequestVariables returns a context keeping the variables of a request
+// as sent, for nullInputFields
+func withRequestVariables(ctx context.Context, variables map[string]interface{}) context.Context {
+	return context.WithValue(ctx, requestVariablesKey{}, variables)
+}
+
+// nullInputFields returns the fields of the input argument of the field
+// resolved by params that were set to null. graphql-go drops null fields
+// when coercing input objects and its parser has no null literal, so nulls
+// can only be sent in variables, read here as sent: either the whole input,
+// as in input:$input, or one of its fields, as in input:{file:$file}.
+func nullInputFields(params graphql.ResolveParams) []string {
+	variables, _ := params.Context.Value(requestVariablesKey{}).(map[string]interface{})
+	if len(params.Info.FieldASTs) == 0 {
+		return nil
+	}
+	var fields []string
+	for _, argument := range params.Info.FieldASTs[0].Arguments {
+		if argument.Name.Value != "input" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.Variable:
+			input, _ := variables[value.Name.Value].(map[string]interface{})
+			for field, v := range input {
+				if v == nil {
+					fields = append(fields, field)
+				}
+			}
+		case *ast.ObjectValue:
+			for _, field := range value.Fields {
+				variable, ok := field.Value.(*ast.Variable)
+				if !ok {
+					continue
+				}
+				if v, ok := variables[variable.Name.Value]; ok && v == nil {
+					fields = append(fields, field.Name.Value)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// updatableFields are the fields of documents update and patch change
+var updatableFields = []string{"name", "file", "contentType", "tags"}
+
+// updateFromFields reads an update from field values, as decoded from JSON:
+// null clears a field, except the name
+func updateFromFields(fields map[string]interface{}) (documentUpdate, error) {
+	update := documentUpdate{}
+	for field, value := range fields {
+		switch field {
+		case "name":
+			if value == nil {
+				return update, errors.New("name can't be null")
+			}
+			name, ok := value.(string)
+			if !ok {
+				return update, errors.New("name must be a string")
+			}
+			update.Name = &name
+		case "file", "contentType":
+			text, ok := value.(string)
+			if !ok && value != nil {
+				return update, fmt.Errorf("%s must be a string or null", field)
+			}
+			if field == "file" {
+				update.File = &text
+			} else {
+				update.ContentType = &text
+			}
+		case "tags":
+			values, ok := value.([]interface{})
+			if !ok && value != nil {
+				return update, errors.New("tags must be a list of strings or null")
+			}
+			for _, v := range values {
+				if _, ok := v.(string); !ok {
+					return update, errors.New("tags must be a list of strings or null")
+				}
+			}
+			tags := stringList(value)
+			update.Tags = &tags
+		default:
+			return update, fmt.Errorf("unknown field %q, fields are %s", field, strings.Join(updatableFields, ", "))
+		}
+	}
+	return update, nil
+}
+
+// apply changes document by update. The content type of a changed file is
+// detected unless the update sets it.
+func (u documentUpdate) apply(document *Document) {
+	if u.Name != nil {
+		document.Name = *u.Name
+	}
+	if u.File != nil {
+		document.setFile(*u.File)
+	}
+	if u.Tags != nil {
+		document.Tags = uniqueTags(*u.Tags)
+	}
+	if u.ContentType != nil {
+		document.ContentType = *u.ContentType
+	} else if u.File != nil {
+		data, _ := decodeFile(document.File)
+		document.ContentType = detectContentType(data)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUpdateInputNulls(t *testing.T) {
+	document := Document{ID: 1, Name: "Report", File: "SGVsbG8=", ContentType: "text/plain", Tags: []string{"draft"}}
+	for _, test := range []struct {
+		input interface{}
+		want  string
+	}{
+		// omitted fields are unchanged
+		{map[string]interface{}{"name": "Renamed"}, "Renamed SGVsbG8= text/plain [draft]"},
+		// null ones are cleared
+		{map[string]interface{}{"tags": nil}, "Report SGVsbG8= text/plain []"},
+		{map[string]interface{}{"contentType": nil}, "Report SGVsbG8=  [draft]"},
+		{map[string]interface{}{"file": nil, "tags": []interface{}{"report"}}, "Report   [report]"},
+	} {
+		t.Run(fmt.Sprint(test.input), func(t *testing.T) {
+			newTestStore(t, document)
+			data := mustExecute(t, newTestSchema(t), "mutation($input:UpdateDocumentInput){update(id:1,input:$input){document{name,file,contentType,tags}}}", map[string]interface{}{
+				"input": test.input,
+			})
+			updated := lookup(data, "update", "document")
+			got := fmt.Sprint(lookup(updated, "name"), " ", lookup(updated, "file"), " ", lookup(updated, "contentType"), " ", lookup(updated, "tags"))
+			if got != test.want {
+				t.Errorf("document %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateInputNullVariableField(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Report", File: "SGVsbG8=", Tags: []string{"draft"}})
+	data := mustExecute(t, newTestSchema(t), "mutation($file:String){update(id:1,input:{file:$file,tags:[]}){document{name,file,tags}}}", map[string]interface{}{
+		"file": nil,
+	})
+	updated := lookup(data, "update", "document")
+	if got := fmt.Sprint(lookup(updated, "name"), " ", lookup(updated, "file"), " ", lookup(updated, "tags")); got != "Report  []" {
+		t.Errorf("document %q, want %q", got, "Report  []")
+	}
+}
+
+func TestUpdateNameCantBeNull(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Report"})
+	result := execute(t.Context(), newTestSchema(t), "mutation($input:UpdateDocumentInput){update(id:1,input:$input){document{name}}}", map[string]interface{}{
+		"input": map[string]interface{}{"name": nil},
+	})
+	if !result.HasErrors() {
+		t.Fatal("update clearing the name succeeded")
+	}
+}
+
+func TestReturnPrevious(t *testing.T) {
+	newTestStore(t, Document{ID: 1, Name: "Old name", Tags: []string{"draft"}})
+	schema := newTestSchema(t)
+	data := mustExecute(t, schema, `mutation{update(id:1,name:"New name",tags:["final"],returnPrevious:true){previous{name,tags},document{name,tags}}}`, nil)
+	if got := fmt.Sprint(lookup(data, "update", "previous")); got != "map[name:Old name tags:[draft]]" {
+		t.Errorf("previous %s, want the old values", got)
+	}
+	if got := fmt.Sprint(lookup(data, "update", "document")); got != "map[name:New name tags:[final]]" {
+		t.Errorf("document %s, want the new values", got)
+	}
+	// previous is null unless asked for
+	data = mustExecute(t, schema, `mutation{update(id:1,name:"Newer name"){previous{name}}}`, nil)
+	if got := lookup(data, "update", "previous"); got != nil {
+		t.Errorf("previous %v without returnPrevious, want null", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// checkExtension returns an error if the file type of a document is not in
+// the allowed extensions. The type is given by extension, or if empty, by the
+// extension of the document name.
+func checkExtension(name, extension string) error {
+	allowed := splitList(*allowedExtensions)
+	if len(allowed) == 0 {
+		return nil
+	}
+	if extension == "" {
+		extension = filepath.Ext(name)
+	}
+	extension = normalizeExtension(extension)
+	for _, a := range allowed {
+		if normalizeExtension(a) == extension {
+			return nil
+		}
+	}
+	if extension == "" {
+		return fmt.Errorf("documents without a file type are not allowed, allowed types are: %s", strings.Join(allowed, ", "))
+	}
+	return fmt.Errorf("file type %q is not allowed, allowed types are: %s", extension, strings.Join(allowed, ", "))
+}
+
+// trimDocument removes the leading and trailing whitespace of the name and
+// tags of document if -trim-whitespace is set, so " Report" and "Report"
+// don't make two different names or tags
+func trimDocument(document *Document) {
+	if !*trimWhitespace {
+		return
+	}
+	document.Name = strings.TrimSpace(document.Name)
+	if document.Tags == nil {
+		return
+	}
+	tags := make([]string, len(document.Tags))
+	for i, tag := range document.Tags {
+		tags[i] = strings.TrimSpace(tag)
+	}
+	// tags differing by whitespace only are now duplicates
+	document.Tags = uniqueTags(tags)
+}
+
+// maxTagLength is the maximum length in bytes of a tag
+const maxTagLength = 64
+
+// checkTags returns an error if a document can't have tags: there are more
+// than -max-tags, or one of them isn't valid
+func checkTags(tags []string) error {
+	if *maxTags > 0 && len(tags) > *maxTags {
+		return fmt.Errorf("documents can have at most %d tags, got %d", *maxTags, len(tags))
+	}
+	for _, tag := range tags {
+		if err := checkTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTag returns an error if tag is empty or too long
+func checkTag(tag string) error {
+	if strings.TrimSpace(tag) == "" {
+		return fmt.Errorf("tags can't be empty")
+	}
+	if len(tag) > maxTagLength {
+		return fmt.Errorf("tag %q is longer than %d bytes", tag, maxTagLength)
+	}
+	return nil
+}
+
+// normalizeExtension lowercases an extension and makes sure it starts with a
+// dot, so "PDF" and ".pdf" compare equal
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(strings.TrimSpace(extension))
+	if extension != "" && !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAllowedExtensions(t *testing.T) {
+	setVar(t, allowedExtensions, ".pdf,.png")
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	mustExecute(t, schema, `mutation{create(name:"report.pdf"){document{id}}}`, nil)
+	mustExecute(t, schema, `mutation{create(name:"Report",extension:"PDF"){document{id}}}`, nil)
+
+	for _, query := range []string{
+		`mutation{create(name:"setup.exe"){document{id}}}`,
+		`mutation{update(id:1,name:"setup.exe"){document{id}}}`,
+		`mutation{create(name:"report.pdf",extension:".exe"){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() {
+			t.Errorf("%s succeeded", query)
+			continue
+		}
+		if message := result.Errors[0].Message; !strings.Contains(message, `".exe" is not allowed`) || !strings.Contains(message, ".pdf, .png") {
+			t.Errorf("%s: error %q, want one listing the allowed types", query, message)
+		}
+	}
+}
+
+func TestCheckExtensionAllowsAllByDefault(t *testing.T) {
+	setVar(t, allowedExtensions, "")
+	if err := checkExtension("setup.exe", ""); err != nil {
+		t.Errorf("checkExtension without allowed extensions: %v", err)
+	}
+}
+
+func TestMaxTags(t *testing.T) {
+	setVar(t, maxTags, 2)
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(name:"New",tags:["a","b"]){document{id}}}`,
+		`mutation{update(id:1,tags:["a","b"]){document{id}}}`,
+	} {
+		if result := execute(context.Background(), schema, query, nil); result.HasErrors() {
+			t.Errorf("%s: %v", query, result.Errors)
+		}
+	}
+	for _, query := range []string{
+		`mutation{create(name:"New",tags:["a","b","c"]){document{id}}}`,
+		`mutation{update(id:1,tags:["a","b","c"]){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "at most 2 tags") {
+			t.Errorf("%s: errors %v, want the tag limit", query, result.Errors)
+		}
+	}
+}
+
+func TestEmptyTag(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(name:"New",tags:["a",""]){document{id}}}`,
+		`mutation{update(id:1,tags:[" "]){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "can't be empty") {
+			t.Errorf("%s: errors %v, want an empty tag error", query, result.Errors)
+		}
+	}
+	if got := lookup(mustExecute(t, schema, `{document(id:1){tags}}`, nil), "document", "tags"); len(got.([]interface{})) != 0 {
+		t.Errorf("tags %v, want none", got)
+	}
+}
+
+func TestTrimWhitespace(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	query := `mutation{create(name:"  Report  ",tags:[" draft","draft "]){document{name,tags}}}`
+	// off by default
+	if got := lookup(mustExecute(t, schema, query, nil), "create", "document", "name"); got != "  Report  " {
+		t.Errorf("name %q without trimming, want it unchanged", got)
+	}
+
+	setVar(t, trimWhitespace, true)
+	data := mustExecute(t, schema, query, nil)
+	if got := lookup(data, "create", "document", "name"); got != "Report" {
+		t.Errorf("created name %q, want Report", got)
+	}
+	if got := fmt.Sprint(lookup(data, "create", "document", "tags")); got != "[draft]" {
+		t.Errorf("created tags %s, want [draft]", got)
+	}
+	data = mustExecute(t, schema, `mutation{update(id:1,name:"\tRenamed\n"){document{name}}}`, nil)
+	if got := lookup(data, "update", "document", "name"); got != "Renamed" {
+		t.Errorf("updated name %q, want Renamed", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Validator checks a document before create or update stores it.
+// Returning an error rejects the document and reports the error to the
+// client.
+type Validator interface {
+	Validate(ctx context.Context, document Document) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface
+type ValidatorFunc func(ctx context.Context, document Document) error
+
+func (f ValidatorFunc) Validate(ctx context.Context, document Document) error {
+	return f(ctx, document)
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []Validator
+)
+
+func init() {
+	RegisterValidator(ValidatorFunc(validateName))
+	RegisterValidator(ValidatorFunc(validateFile))
+}
+
+// RegisterValidator adds a validator run on documents before they are
+// created or updated, after the validators registered before it. Like
+// mutation hooks, validators are typically registered from an init function
+// in a file added to this package.
+func RegisterValidator(validator Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append(validators, validator)
+}
+
+// validateDocument runs the registered validators in order, stopping at the
+// first error
+func validateDocument(ctx context.Context, document Document) error {
+	validatorsMu.RLock()
+	registered := validators
+	validatorsMu.RUnlock()
+	for _, validator := range registered {
+		if err := validator.Validate(ctx, document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateName rejects documents without a name
+func validateName(ctx context.Context, document Document) error {
+	if strings.TrimSpace(document.Name) == "" {
+		return errors.New("name can't be empty")
+	}
+	return nil
+}
+
+// validateFile rejects files which are not base64 encoded. Files kept in
+// the blob store were checked when stored.
+func validateFile(ctx context.Context, document Document) error {
+	if document.File == "" {
+		return nil
+	}
+	if _, err := base64.StdEncoding.Strict().DecodeString(document.File); err != nil {
+		return errors.New("file must be base64 encoded")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterValidator(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	setVar(t, &validators, validators[:len(validators):len(validators)])
+	RegisterValidator(ValidatorFunc(func(ctx context.Context, document Document) error {
+		if !strings.HasPrefix(document.Name, "doc-") {
+			return errors.New(`names must start with "doc-"`)
+		}
+		return nil
+	}))
+	schema := newTestSchema(t)
+
+	for _, query := range []string{
+		`mutation{create(name:"report"){document{id}}}`,
+		`mutation{update(id:1,name:"report"){document{id}}}`,
+	} {
+		result := execute(context.Background(), schema, query, nil)
+		if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, `start with "doc-"`) {
+			t.Errorf("%s: errors %v, want the custom validator's", query, result.Errors)
+		}
+	}
+	mustExecute(t, schema, `mutation{create(name:"doc-report"){document{id}}}`, nil)
+	// the built-in validators still run first
+	result := execute(context.Background(), schema, `mutation{create(name:" "){document{id}}}`, nil)
+	if !result.HasErrors() || !strings.Contains(result.Errors[0].Message, "name can't be empty") {
+		t.Errorf("empty name: errors %v, want the built-in validator's", result.Errors)
+	}
+}
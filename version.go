@@ -0,0 +1,44 @@
+package main
+
+import "github.com/graphql-go/graphql"
+
+// schemaVersion is a version of the API schema, served on /<name>/document.
+// All versions share the same store.
+type schemaVersion struct {
+	name string
+	// fields restricts the fields of the listed types; types that are not
+	// listed keep all their fields
+	fields map[string][]string
+}
+
+// schemaVersions are the versions of the API, oldest first. The latest one
+// is also served on /document.
+var schemaVersions = []schemaVersion{
+	{
+		// the original API
+		name: "v1",
+		fields: map[string][]string{
+			documentTypeName: {"id", "name", "file"},
+			"Query":          {"document", "list"},
+			"Mutation":       {"create", "update", "delete"},
+		},
+	},
+	{
+		name: "v2",
+	},
+}
+
+// selectFields returns the fields of typeName that are part of the version
+func (v schemaVersion) selectFields(typeName string, fields graphql.Fields) graphql.Fields {
+	names, ok := v.fields[typeName]
+	if !ok {
+		return fields
+	}
+	selected := graphql.Fields{}
+	for _, name := range names {
+		if field, ok := fields[name]; ok {
+			selected[name] = field
+		}
+	}
+	return selected
+}
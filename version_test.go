@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersions(t *testing.T) {
+	newTestStore(t, testDocuments(1)...)
+	mux := http.NewServeMux()
+	for _, version := range schemaVersions {
+		schema, err := newSchema(version)
+		if err != nil {
+			t.Fatalf("newSchema(%s): %v", version.name, err)
+		}
+		mux.HandleFunc("/"+version.name+"/document", documentHandler(schema))
+	}
+	post := func(path, query string) map[string]interface{} {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(query))
+		r.Header.Set("Content-Type", "application/graphql")
+		return decodeResponse(t, serve(mux, r))
+	}
+
+	// v1 has the original fields only, v2 all of them
+	v1 := post("/v1/document", "{document(id:1){name,tags}}")
+	if errors, _ := v1["errors"].([]interface{}); len(errors) == 0 || !strings.Contains(lookup(errors[0], "message").(string), `Cannot query field "tags"`) {
+		t.Errorf("v1 response %v, want an error for tags", v1)
+	}
+	if got := lookup(post("/v1/document", "{document(id:1){name}}"), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("v1 name = %v, want Document 1", got)
+	}
+	if got := lookup(post("/v2/document", "{document(id:1){name,tags}}"), "data", "document", "name"); got != "Document 1" {
+		t.Errorf("v2 name = %v, want Document 1", got)
+	}
+	// both share the store
+	post("/v2/document", `mutation{update(id:1,name:"Renamed"){document{id}}}`)
+	if got := lookup(post("/v1/document", "{document(id:1){name}}"), "data", "document", "name"); got != "Renamed" {
+		t.Errorf("v1 name after an update through v2 = %v, want Renamed", got)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// notifyWebhooks posts event to the configured webhook URLs, or adds it to
+// the current batch if -webhook-coalesce is set. Deliveries run in the
+// background so they never hold up the mutation.
+func notifyWebhooks(event changeEvent) {
+	if len(splitList(*webhookURLs)) == 0 {
+		return
+	}
+	if *webhookCoalesce > 0 {
+		batches.add(event)
+		return
+	}
+	postWebhooks(event)
+}
+
+// postWebhooks posts payload as JSON to the configured webhook URLs
+func postWebhooks(payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhook: %v", err)
+		return
+	}
+	for _, url := range splitList(*webhookURLs) {
+		go deliverWebhook(url, body)
+	}
+}
+
+// coalescedEvents is the payload of a batch of events
+type coalescedEvents struct {
+	Type   string        `json:"type"` // always documentsChanged
+	Events []changeEvent `json:"events"`
+}
+
+// eventBatch collects the events happening within -webhook-coalesce of the
+// first one, to post them all at once
+type eventBatch struct {
+	mu     sync.Mutex
+	events []changeEvent
+}
+
+var batches eventBatch
+
+// add adds event to the batch, starting a new one if there is none
+func (b *eventBatch) add(event changeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) == 0 {
+		time.AfterFunc(*webhookCoalesce, b.flush)
+	}
+	b.events = append(b.events, event)
+}
+
+// flush posts the events of the batch and starts over
+func (b *eventBatch) flush() {
+	b.mu.Lock()
+	events := b.events
+	b.events = nil
+	b.mu.Unlock()
+	postWebhooks(coalescedEvents{Type: "documentsChanged", Events: events})
+}
+
+// deliverWebhook posts body to url, retrying with exponential backoff until
+// it is accepted or -webhook-attempts is reached
+func deliverWebhook(url string, body []byte) {
+	client := &http.Client{Timeout: *webhookTimeout}
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		if attempt >= *webhookAttempts {
+			log.Printf("webhook %s: giving up after %d attempts: %v", url, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookReceiver starts a server receiving webhook posts, answering with
+// status, and returns the bodies it receives
+func webhookReceiver(t *testing.T, status func() int) <-chan map[string]interface{} {
+	t.Helper()
+	received := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if code := status(); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid webhook payload %q", body)
+		}
+		received <- payload
+	}))
+	t.Cleanup(server.Close)
+	setVar(t, webhookURLs, server.URL)
+	return received
+}
+
+// nextPayload waits for a payload from received
+func nextPayload(t *testing.T, received <-chan map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	select {
+	case payload := <-received:
+		return payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received")
+		return nil
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := webhookReceiver(t, func() int { return http.StatusOK })
+	newTestStore(t)
+	mustExecute(t, newTestSchema(t), `mutation{create(name:"Report"){document{id}}}`, nil)
+	payload := nextPayload(t, received)
+	if payload["type"] != "create" || lookup(payload, "document", "name") != "Report" {
+		t.Errorf("webhook payload %v, want the create of Report", payload)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	// the first delivery fails, the second is accepted
+	var deliveries atomic.Int32
+	received := webhookReceiver(t, func() int {
+		if deliveries.Add(1) == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	newTestStore(t)
+	start := time.Now()
+	mustExecute(t, newTestSchema(t), `mutation{create(name:"Report"){document{id}}}`, nil)
+	// the mutation doesn't wait for the delivery
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("the mutation took %v", elapsed)
+	}
+	if payload := nextPayload(t, received); payload["type"] != "create" {
+		t.Errorf("webhook payload %v, want the create", payload)
+	}
+	if n := deliveries.Load(); n != 2 {
+		t.Errorf("%d deliveries, want 2", n)
+	}
+}
+
+func TestWebhookCoalesce(t *testing.T) {
+	received := webhookReceiver(t, func() int { return http.StatusOK })
+	setVar(t, webhookCoalesce, 200*time.Millisecond)
+	newTestStore(t, testDocuments(1)...)
+	schema := newTestSchema(t)
+	for _, query := range []string{
+		`mutation{create(name:"Report"){document{id}}}`,
+		`mutation{update(id:1,name:"Renamed"){document{id}}}`,
+		`mutation{delete(id:2){deleted}}`,
+	} {
+		mustExecute(t, schema, query, nil)
+	}
+	payload := nextPayload(t, received)
+	events, _ := payload["events"].([]interface{})
+	if payload["type"] != "documentsChanged" || len(events) != 3 {
+		t.Fatalf("webhook payload %v, want the 3 events in one batch", payload)
+	}
+	for i, want := range []string{"create", "update", "delete"} {
+		if got := lookup(events[i], "type"); got != want {
+			t.Errorf("event %d is %v, want %s", i, got, want)
+		}
+	}
+	select {
+	case payload := <-received:
+		t.Errorf("another webhook %v, want one batch", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+}